@@ -0,0 +1,197 @@
+// Package config loads the service-configuration from the environment, the
+// ".env" file and an optional YAML file.
+//
+// Values are applied in order: defaults, then YAML file, then env-vars.
+// Each field is set from the env-var in its "env" tag, and from the YAML key
+// in its "yaml" tag. Fields tagged `secret:"true"` are redacted when printed.
+package config
+
+// Config is the service-configuration.
+type Config struct {
+	Kafka      Kafka      `yaml:"kafka"`
+	Mongo      Mongo      `yaml:"mongo"`
+	Outbox     Outbox     `yaml:"outbox"`
+	Results    Results    `yaml:"results"`
+	Expiry     Expiry     `yaml:"expiry"`
+	Handlers   Handlers   `yaml:"handlers"`
+	Metrics    Metrics    `yaml:"metrics"`
+	Authz      Authz      `yaml:"authz"`
+	Approval   Approval   `yaml:"approval"`
+	Recurrence Recurrence `yaml:"recurrence"`
+}
+
+// Kafka is the configuration for Kafka consumers and producers.
+type Kafka struct {
+	Brokers []string `yaml:"brokers" env:"KAFKA_BROKERS"`
+
+	ConsumerEventGroup      string `yaml:"consumerEventGroup" env:"KAFKA_CONSUMER_EVENT_GROUP"`
+	ConsumerEventQueryGroup string `yaml:"consumerEventQueryGroup" env:"KAFKA_CONSUMER_EVENT_QUERY_GROUP"`
+
+	ConsumerEventTopic      string `yaml:"consumerEventTopic" env:"KAFKA_CONSUMER_EVENT_TOPIC"`
+	ConsumerEventQueryTopic string `yaml:"consumerEventQueryTopic" env:"KAFKA_CONSUMER_EVENT_QUERY_TOPIC"`
+	ProducerEventTopic      string `yaml:"producerEventTopic" env:"KAFKA_PRODUCER_EVENT_TOPIC"`
+	ProducerEventQueryTopic string `yaml:"producerEventQueryTopic" env:"KAFKA_PRODUCER_EVENT_QUERY_TOPIC"`
+	ProducerResponseTopic   string `yaml:"producerResponseTopic" env:"KAFKA_PRODUCER_RESPONSE_TOPIC"`
+
+	TLS  KafkaTLS  `yaml:"tls"`
+	SASL KafkaSASL `yaml:"sasl"`
+}
+
+// KafkaTLS is the TLS-configuration for connecting to Kafka.
+type KafkaTLS struct {
+	Enabled            bool   `yaml:"enabled" env:"KAFKA_TLS_ENABLED"`
+	CAFile             string `yaml:"caFile" env:"KAFKA_TLS_CA_FILE"`
+	CertFile           string `yaml:"certFile" env:"KAFKA_TLS_CERT_FILE"`
+	KeyFile            string `yaml:"keyFile" env:"KAFKA_TLS_KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" env:"KAFKA_TLS_INSECURE_SKIP_VERIFY"`
+}
+
+// KafkaSASL is the SASL-configuration for authenticating with Kafka.
+// The username and password are read from UsernameFile and PasswordFile if set,
+// such as for mounted secrets.
+type KafkaSASL struct {
+	// Mechanism is one of "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512".
+	// SASL is disabled if this is empty.
+	Mechanism    string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"`
+	Username     string `yaml:"username" env:"KAFKA_SASL_USERNAME"`
+	UsernameFile string `yaml:"usernameFile" env:"KAFKA_SASL_USERNAME_FILE"`
+	Password     string `yaml:"password" env:"KAFKA_SASL_PASSWORD" secret:"true"`
+	PasswordFile string `yaml:"passwordFile" env:"KAFKA_SASL_PASSWORD_FILE"`
+}
+
+// Mongo is the configuration for MongoDB.
+type Mongo struct {
+	Hosts    []string `yaml:"hosts" env:"MONGO_HOSTS"`
+	Username string   `yaml:"username" env:"MONGO_USERNAME"`
+	Password string   `yaml:"password" env:"MONGO_PASSWORD" secret:"true"`
+
+	Database             string `yaml:"database" env:"MONGO_DATABASE"`
+	AggCollection        string `yaml:"aggCollection" env:"MONGO_AGG_COLLECTION"`
+	MetaCollection       string `yaml:"metaCollection" env:"MONGO_META_COLLECTION"`
+	InventoryCollection  string `yaml:"inventoryCollection" env:"MONGO_INVENTORY_COLLECTION"`
+	TemplateCollection   string `yaml:"templateCollection" env:"MONGO_TEMPLATE_COLLECTION"`
+	AuditCollection      string `yaml:"auditCollection" env:"MONGO_AUDIT_COLLECTION"`
+	RecurrenceCollection string `yaml:"recurrenceCollection" env:"MONGO_RECURRENCE_COLLECTION"`
+	OutboxCollection     string `yaml:"outboxCollection" env:"MONGO_OUTBOX_COLLECTION"`
+
+	ConnectionTimeoutMS uint32 `yaml:"connectionTimeoutMS" env:"MONGO_CONNECTION_TIMEOUT_MS"`
+	ResourceTimeoutMS   uint32 `yaml:"resourceTimeoutMS" env:"MONGO_RESOURCE_TIMEOUT_MS"`
+
+	// SchemaValidation is one of "strict", "warn" or "off".
+	SchemaValidation string `yaml:"schemaValidation" env:"MONGO_SCHEMA_VALIDATION"`
+
+	TLS MongoTLS `yaml:"tls"`
+
+	AuthSource     string `yaml:"authSource" env:"MONGO_AUTH_SOURCE"`
+	ReplicaSet     string `yaml:"replicaSet" env:"MONGO_REPLICA_SET"`
+	ReadPreference string `yaml:"readPreference" env:"MONGO_READ_PREFERENCE"`
+	// WriteConcern is "majority", or the number of acknowledging members.
+	WriteConcern string `yaml:"writeConcern" env:"MONGO_WRITE_CONCERN"`
+}
+
+// MongoTLS is the TLS-configuration for connecting to MongoDB.
+type MongoTLS struct {
+	Enabled      bool   `yaml:"enabled" env:"MONGO_TLS_ENABLED"`
+	CAFile       string `yaml:"caFile" env:"MONGO_TLS_CA_FILE"`
+	CertKeyFile  string `yaml:"certKeyFile" env:"MONGO_TLS_CERT_KEY_FILE"`
+	AllowInvalid bool   `yaml:"allowInvalid" env:"MONGO_TLS_INSECURE"`
+}
+
+// Outbox is the configuration for the OutboxRelay.
+type Outbox struct {
+	IntervalMS int `yaml:"intervalMS" env:"OUTBOX_RELAY_INTERVAL_MS"`
+	BatchSize  int `yaml:"batchSize" env:"OUTBOX_RELAY_BATCH_SIZE"`
+}
+
+// Results is the configuration for the FlashSales included in results.
+type Results struct {
+	MaxReturnDocuments int `yaml:"maxReturnDocuments" env:"FLASHSALE_MAX_RETURN_DOCUMENTS"`
+}
+
+// Expiry is the configuration for the ExpiryScheduler.
+type Expiry struct {
+	Enabled       bool   `yaml:"enabled" env:"EXPIRY_SCHEDULER_ENABLED"`
+	IntervalSec   int    `yaml:"intervalSec" env:"EXPIRY_SCHEDULER_INTERVAL_SEC"`
+	AutoCreate    bool   `yaml:"autoCreate" env:"EXPIRY_SALE_AUTO_CREATE"`
+	SaleDays      int    `yaml:"saleDays" env:"EXPIRY_SALE_DAYS"`
+	DiscountCurve string `yaml:"discountCurve" env:"EXPIRY_SALE_DISCOUNT_CURVE"`
+	// TimeZone is the IANA time zone in which the days to expiry are counted.
+	TimeZone string `yaml:"timeZone" env:"EXPIRY_TIME_ZONE"`
+}
+
+// Handlers is the configuration for the middlewares around Event-handlers.
+type Handlers struct {
+	// DedupSize is the number of recent Event-UUIDs remembered for skipping
+	// redelivered Events. Deduplication is disabled if this is 0.
+	DedupSize int `yaml:"dedupSize" env:"HANDLER_DEDUP_SIZE"`
+}
+
+// Metrics is the configuration for serving the service-metrics.
+type Metrics struct {
+	// Addr is the address for serving the expvar-metrics on "/debug/vars".
+	// The metrics are not served if this is empty.
+	Addr string `yaml:"addr" env:"METRICS_ADDR"`
+}
+
+// Authz is the configuration for authorizing users to run Events.
+type Authz struct {
+	Enabled bool `yaml:"enabled" env:"AUTHZ_ENABLED"`
+	// RolesFile is the YAML file mapping the UserUUIDs to their roles.
+	RolesFile string `yaml:"rolesFile" env:"AUTHZ_ROLES_FILE"`
+	// ServiceUser is the UserUUID of the Events created by the service itself,
+	// such as by the schedulers. It should have the "service" role in RolesFile.
+	ServiceUser string `yaml:"serviceUser" env:"AUTHZ_SERVICE_USER"`
+}
+
+// Approval is the configuration for holding new FlashSales for approval.
+// A threshold of 0 disables the check.
+type Approval struct {
+	// DiscountThreshold is the item-Discount (percentage) above which
+	// a FlashSale requires approval.
+	DiscountThreshold float64 `yaml:"discountThreshold" env:"APPROVAL_DISCOUNT_THRESHOLD"`
+	// WeightThreshold is the total Weight of discounted items above which
+	// a FlashSale requires approval.
+	WeightThreshold float64 `yaml:"weightThreshold" env:"APPROVAL_WEIGHT_THRESHOLD"`
+}
+
+// Recurrence is the configuration for the RecurrenceScheduler.
+type Recurrence struct {
+	Enabled     bool `yaml:"enabled" env:"RECURRENCE_SCHEDULER_ENABLED"`
+	IntervalSec int  `yaml:"intervalSec" env:"RECURRENCE_SCHEDULER_INTERVAL_SEC"`
+	// LookaheadDays is how many days ahead of their start the occurrences of
+	// recurring FlashSales are created.
+	LookaheadDays int `yaml:"lookaheadDays" env:"RECURRENCE_LOOKAHEAD_DAYS"`
+}
+
+// Default returns the Config with default values.
+func Default() *Config {
+	return &Config{
+		Mongo: Mongo{
+			TemplateCollection:   "agg_flashSale_template",
+			AuditCollection:      "agg_flashSale_audit",
+			RecurrenceCollection: "agg_flashSale_recurrence",
+			OutboxCollection:     "agg_flashSale_outbox",
+			ConnectionTimeoutMS:  3000,
+			ResourceTimeoutMS:    5000,
+			SchemaValidation:     "strict",
+		},
+		Outbox: Outbox{
+			IntervalMS: 500,
+			BatchSize:  100,
+		},
+		Results: Results{
+			MaxReturnDocuments: 100,
+		},
+		Expiry: Expiry{
+			IntervalSec: 3600,
+			SaleDays:    3,
+		},
+		Handlers: Handlers{
+			DedupSize: 10000,
+		},
+		Recurrence: Recurrence{
+			IntervalSec:   3600,
+			LookaheadDays: 7,
+		},
+	}
+}
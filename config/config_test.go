@@ -0,0 +1,238 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config Suite")
+}
+
+// writeTempFile writes the content to a temporary file and returns its path.
+func writeTempFile(content string) string {
+	file, err := ioutil.TempFile("", "config")
+	Expect(err).ToNot(HaveOccurred())
+	_, err = file.WriteString(content)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(file.Close()).To(Succeed())
+	return file.Name()
+}
+
+var _ = Describe("Config", func() {
+	var (
+		envVars   []string
+		tempFiles []string
+	)
+
+	setenv := func(key, value string) {
+		envVars = append(envVars, key)
+		os.Setenv(key, value)
+	}
+
+	// validConfig returns a Config which passes validation.
+	validConfig := func() *Config {
+		c := Default()
+		c.Kafka = Kafka{
+			Brokers:                 []string{"kafka:9092"},
+			ConsumerEventGroup:      "event.group",
+			ConsumerEventQueryGroup: "eq.group",
+			ConsumerEventTopic:      "event.response",
+			ConsumerEventQueryTopic: "esquery.response",
+			ProducerEventTopic:      "events",
+			ProducerEventQueryTopic: "esquery.request",
+			ProducerResponseTopic:   "flashSale.response",
+		}
+		c.Mongo.Hosts = []string{"mongo:27017"}
+		c.Mongo.Database = "db"
+		c.Mongo.AggCollection = "agg_flashSale"
+		c.Mongo.MetaCollection = "aggregate_meta"
+		return c
+	}
+
+	BeforeEach(func() {
+		envVars = []string{}
+		tempFiles = []string{}
+	})
+
+	AfterEach(func() {
+		for _, key := range envVars {
+			os.Unsetenv(key)
+		}
+		for _, path := range tempFiles {
+			os.Remove(path)
+		}
+	})
+
+	Describe("Load", func() {
+		It("should use defaults for values not set", func() {
+			c, err := Load("./missing.env")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(Equal(Default()))
+		})
+
+		It("should read typed values from env-vars", func() {
+			setenv("KAFKA_BROKERS", "kafka1:9092,kafka2:9092")
+			setenv("MONGO_RESOURCE_TIMEOUT_MS", "7000")
+			setenv("OUTBOX_RELAY_BATCH_SIZE", "25")
+			setenv("EXPIRY_SCHEDULER_ENABLED", "true")
+			setenv("APPROVAL_DISCOUNT_THRESHOLD", "40.5")
+
+			c, err := Load("./missing.env")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Kafka.Brokers).To(Equal([]string{"kafka1:9092", "kafka2:9092"}))
+			Expect(c.Mongo.ResourceTimeoutMS).To(Equal(uint32(7000)))
+			Expect(c.Outbox.BatchSize).To(Equal(25))
+			Expect(c.Expiry.Enabled).To(BeTrue())
+			Expect(c.Approval.DiscountThreshold).To(Equal(40.5))
+		})
+
+		It("should return error for invalid types", func() {
+			setenv("MONGO_CONNECTION_TIMEOUT_MS", "three")
+			_, err := Load("./missing.env")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should override YAML-values with env-vars", func() {
+			path := writeTempFile(strings.Join([]string{
+				"mongo:",
+				"  database: yaml_db",
+				"  aggCollection: yaml_agg",
+				"outbox:",
+				"  batchSize: 10",
+			}, "\n"))
+			tempFiles = append(tempFiles, path)
+			setenv("CONFIG_FILE", path)
+			setenv("MONGO_DATABASE", "env_db")
+
+			c, err := Load("./missing.env")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Mongo.Database).To(Equal("env_db"))
+			Expect(c.Mongo.AggCollection).To(Equal("yaml_agg"))
+			Expect(c.Outbox.BatchSize).To(Equal(10))
+		})
+
+		It("should apply env-file below YAML-values and env-vars", func() {
+			yamlPath := writeTempFile(strings.Join([]string{
+				"mongo:",
+				"  database: yaml_db",
+			}, "\n"))
+			envPath := writeTempFile(strings.Join([]string{
+				"CONFIG_FILE=" + yamlPath,
+				"MONGO_DATABASE=dotenv_db",
+				"MONGO_AGG_COLLECTION=dotenv_agg",
+				"MONGO_META_COLLECTION=dotenv_meta",
+			}, "\n"))
+			tempFiles = append(tempFiles, yamlPath, envPath)
+			setenv("MONGO_AGG_COLLECTION", "env_agg")
+
+			c, err := Load(envPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Mongo.Database).To(Equal("yaml_db"))
+			Expect(c.Mongo.AggCollection).To(Equal("env_agg"))
+			Expect(c.Mongo.MetaCollection).To(Equal("dotenv_meta"))
+			// The env-file is not loaded into environment
+			Expect(os.Getenv("MONGO_META_COLLECTION")).To(BeEmpty())
+		})
+
+		It("should read Kafka SASL-credentials from files", func() {
+			path := writeTempFile("file-secret\n")
+			tempFiles = append(tempFiles, path)
+			setenv("KAFKA_SASL_PASSWORD", "ignored")
+			setenv("KAFKA_SASL_PASSWORD_FILE", path)
+
+			c, err := Load("./missing.env")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Kafka.SASL.Password).To(Equal("file-secret"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should accept valid Config", func() {
+			Expect(validConfig().Validate()).To(Succeed())
+		})
+
+		It("should return error for missing required values", func() {
+			c := validConfig()
+			c.Kafka.ProducerResponseTopic = ""
+			Expect(c.Validate()).ToNot(Succeed())
+
+			c = validConfig()
+			c.Mongo.Hosts = nil
+			Expect(c.Validate()).ToNot(Succeed())
+		})
+
+		It("should return error for out of range values", func() {
+			c := validConfig()
+			c.Outbox.IntervalMS = 0
+			Expect(c.Validate()).ToNot(Succeed())
+
+			c = validConfig()
+			c.Results.MaxReturnDocuments = -1
+			Expect(c.Validate()).ToNot(Succeed())
+
+			c = validConfig()
+			c.Mongo.WriteConcern = "all"
+			Expect(c.Validate()).ToNot(Succeed())
+
+			c = validConfig()
+			c.Handlers.DedupSize = -1
+			Expect(c.Validate()).ToNot(Succeed())
+		})
+
+		It("should validate ExpiryScheduler only if enabled", func() {
+			c := validConfig()
+			c.Expiry.SaleDays = 0
+			Expect(c.Validate()).To(Succeed())
+
+			c.Expiry.Enabled = true
+			c.Mongo.InventoryCollection = "agg_inventory"
+			Expect(c.Validate()).ToNot(Succeed())
+		})
+
+		It("should validate RecurrenceScheduler only if enabled", func() {
+			c := validConfig()
+			c.Recurrence.LookaheadDays = 0
+			Expect(c.Validate()).To(Succeed())
+
+			c.Recurrence.Enabled = true
+			Expect(c.Validate()).ToNot(Succeed())
+		})
+
+		It("should require roles-file if authorization is enabled", func() {
+			c := validConfig()
+			c.Authz.Enabled = true
+			Expect(c.Validate()).ToNot(Succeed())
+		})
+
+		It("should require ServiceUser to be a UUID", func() {
+			c := validConfig()
+			c.Authz.ServiceUser = "scheduler"
+			Expect(c.Validate()).ToNot(Succeed())
+
+			c.Authz.ServiceUser = "6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e"
+			Expect(c.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("String", func() {
+		It("should redact secrets", func() {
+			c := validConfig()
+			c.Mongo.Password = "mongo-secret"
+			c.Kafka.SASL.Password = "kafka-secret"
+
+			printed := c.String()
+			Expect(printed).To(ContainSubstring("MONGO_DATABASE=db"))
+			Expect(printed).To(ContainSubstring("KAFKA_BROKERS=kafka:9092"))
+			Expect(printed).To(ContainSubstring("MONGO_PASSWORD=" + redacted))
+			Expect(printed).ToNot(ContainSubstring("mongo-secret"))
+			Expect(printed).ToNot(ContainSubstring("kafka-secret"))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"github.com/xdg/scram"
+)
+
+// SaramaConfig returns a sarama-config with the TLS and SASL configuration.
+// This is used as SaramaConfig for all Kafka consumers and producers, so they
+// connect using same security settings. A new sarama-config is returned
+// for each call, since clients modify their configs.
+func (k *Kafka) SaramaConfig() (*sarama.Config, error) {
+	err := k.ValidateSecurity()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Kafka security-configuration")
+		return nil, err
+	}
+
+	config := sarama.NewConfig()
+	// Consumer-groups require Kafka version 0.10.2 or later
+	config.Version = sarama.V2_0_0_0
+	config.Consumer.Return.Errors = true
+	config.Producer.Return.Errors = true
+
+	if k.TLS.Enabled {
+		tlsConfig, err := k.TLS.tlsConfig()
+		if err != nil {
+			err = errors.Wrap(err, "Error creating Kafka TLS-config")
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	mechanism := strings.ToUpper(k.SASL.Mechanism)
+	if mechanism != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Handshake = true
+		config.Net.SASL.Mechanism = sarama.SASLMechanism(mechanism)
+		config.Net.SASL.User = k.SASL.Username
+		config.Net.SASL.Password = k.SASL.Password
+
+		switch mechanism {
+		case sarama.SASLTypeSCRAMSHA256:
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: sha256.New}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: sha512.New}
+			}
+		}
+	}
+	return config, nil
+}
+
+func (t *KafkaTLS) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		caCert, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			err = errors.Wrap(err, "Error reading KAFKA_TLS_CA_FILE")
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("no certificates found in KAFKA_TLS_CA_FILE")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			err = errors.Wrap(err, "Error loading Kafka client-certificate")
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// scramClient implements sarama.SCRAMClient.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	hashGenerator scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating SCRAM-client")
+		return err
+	}
+	c.Client = client
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
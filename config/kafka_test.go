@@ -0,0 +1,63 @@
+package config
+
+import (
+	"github.com/Shopify/sarama"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kafka", func() {
+	var k *Kafka
+
+	BeforeEach(func() {
+		k = &Kafka{
+			Brokers: []string{"kafka:9092"},
+		}
+	})
+
+	It("should disable TLS and SASL if not configured", func() {
+		config, err := k.SaramaConfig()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Net.TLS.Enable).To(BeFalse())
+		Expect(config.Net.SASL.Enable).To(BeFalse())
+	})
+
+	It("should configure SCRAM authentication", func() {
+		k.SASL = KafkaSASL{
+			Mechanism: "scram-sha-512",
+			Username:  "user",
+			Password:  "secret",
+		}
+
+		config, err := k.SaramaConfig()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Net.SASL.Enable).To(BeTrue())
+		Expect(config.Net.SASL.Mechanism).To(
+			Equal(sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512)),
+		)
+		Expect(config.Net.SASL.User).To(Equal("user"))
+		Expect(config.Net.SASL.Password).To(Equal("secret"))
+		Expect(config.Net.SASL.SCRAMClientGeneratorFunc).ToNot(BeNil())
+	})
+
+	It("should return error for unsupported SASL mechanisms", func() {
+		k.SASL.Mechanism = "GSSAPI"
+		_, err := k.SaramaConfig()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if SASL credentials are missing", func() {
+		k.SASL = KafkaSASL{
+			Mechanism: "SCRAM-SHA-256",
+			Username:  "user",
+		}
+		_, err := k.SaramaConfig()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if TLS-files are set without enabling TLS", func() {
+		k.TLS.CAFile = "/etc/kafka/ca.pem"
+		_, err := k.SaramaConfig()
+		Expect(err).To(HaveOccurred())
+	})
+})
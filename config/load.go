@@ -0,0 +1,187 @@
+package config
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Load returns the Config read from the YAML file, overridden by the env-vars.
+// The env-file, if it exists, is read as a layer below the YAML file, so it
+// provides local defaults without overriding the YAML file or the env-vars.
+// The env-file is not loaded into the environment.
+// The YAML file is read from path in "CONFIG_FILE" env-var, and is optional.
+// The returned Config is not validated.
+func Load(envFile string) (*Config, error) {
+	envFileVars, err := godotenv.Read(envFile)
+	if err != nil {
+		err = errors.Wrapf(err,
+			"%s file not found, env-vars will be read as set in environment", envFile,
+		)
+		log.Println(err)
+		envFileVars = map[string]string{}
+	}
+
+	c := Default()
+	err = loadEnv(reflect.ValueOf(c).Elem(), func(key string) string {
+		return envFileVars[key]
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "Error reading env-vars from %s", envFile)
+		return nil, err
+	}
+
+	yamlFile := os.Getenv("CONFIG_FILE")
+	if yamlFile == "" {
+		yamlFile = envFileVars["CONFIG_FILE"]
+	}
+	if yamlFile != "" {
+		err = c.loadYAML(yamlFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = loadEnv(reflect.ValueOf(c).Elem(), os.Getenv)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading env-vars")
+		return nil, err
+	}
+
+	err = c.Kafka.SASL.readFiles()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadYAML sets the Config-values present in the YAML file.
+func (c *Config) loadYAML(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = errors.Wrapf(err, "Error reading config-file %s", path)
+		return err
+	}
+	err = yaml.UnmarshalStrict(data, c)
+	if err != nil {
+		err = errors.Wrapf(err, "Error parsing config-file %s", path)
+		return err
+	}
+	return nil
+}
+
+// readFiles reads the credentials from UsernameFile and PasswordFile, if set.
+func (s *KafkaSASL) readFiles() error {
+	files := []struct {
+		path string
+		dest *string
+	}{
+		{s.UsernameFile, &s.Username},
+		{s.PasswordFile, &s.Password},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		value, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			err = errors.Wrapf(
+				err, "Error reading Kafka SASL-credentials from %s", f.path,
+			)
+			return err
+		}
+		*f.dest = strings.TrimSpace(string(value))
+	}
+	return nil
+}
+
+// loadEnv sets the struct-fields from the env-vars in their "env" tags, as
+// returned by getenv. Env-vars which are not set or are blank are skipped.
+func loadEnv(v reflect.Value, getenv func(key string) string) error {
+	return walkFields(v, func(field reflect.StructField, value reflect.Value) error {
+		envVar := field.Tag.Get("env")
+		raw := strings.TrimSpace(getenv(envVar))
+		if raw == "" {
+			return nil
+		}
+		return setValue(envVar, value, raw)
+	})
+}
+
+// walkFields calls fn for each field with an "env" tag, including the fields of
+// nested structs.
+func walkFields(
+	v reflect.Value,
+	fn func(field reflect.StructField, value reflect.Value) error,
+) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		if field.Tag.Get("env") == "" {
+			if value.Kind() == reflect.Struct {
+				err := walkFields(value, fn)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		err := fn(field, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setValue parses the raw value according to kind of field, and sets it.
+func setValue(envVar string, value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.Errorf("%s must be true or false, got: %s", envVar, raw)
+		}
+		value.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.Errorf("%s must be an integer, got: %s", envVar, raw)
+		}
+		value.SetInt(int64(i))
+	case reflect.Uint32:
+		u, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return errors.Errorf("%s must be a positive integer, got: %s", envVar, raw)
+		}
+		value.SetUint(u)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.Errorf("%s must be a number, got: %s", envVar, raw)
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		values := make([]string, 0)
+		for _, v := range strings.Split(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+		value.Set(reflect.ValueOf(values))
+	default:
+		return errors.Errorf("unsupported type for %s: %s", envVar, value.Type())
+	}
+	return nil
+}
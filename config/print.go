@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces the values of secret fields when printing the Config.
+const redacted = "******"
+
+// String returns the effective Config as env-vars, one per line,
+// with the secret values redacted.
+func (c *Config) String() string {
+	lines := make([]string, 0)
+	walkFields(
+		reflect.ValueOf(c).Elem(),
+		func(field reflect.StructField, value reflect.Value) error {
+			valueStr := fmt.Sprint(value.Interface())
+			if value.Kind() == reflect.Slice {
+				valueStr = strings.Trim(valueStr, "[]")
+				valueStr = strings.Replace(valueStr, " ", ",", -1)
+			}
+			if field.Tag.Get("secret") == "true" && valueStr != "" {
+				valueStr = redacted
+			}
+			lines = append(lines, fmt.Sprintf("%s=%s", field.Tag.Get("env"), valueStr))
+			return nil
+		},
+	)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// mongoReadPreferences are the supported MongoDB read-preference modes.
+var mongoReadPreferences = map[string]bool{
+	"primary":            true,
+	"primaryPreferred":   true,
+	"secondary":          true,
+	"secondaryPreferred": true,
+	"nearest":            true,
+}
+
+// kafkaSASLMechanisms are the supported Kafka SASL-mechanisms.
+var kafkaSASLMechanisms = map[string]bool{
+	"PLAIN":         true,
+	"SCRAM-SHA-256": true,
+	"SCRAM-SHA-512": true,
+}
+
+// Validate validates the Config required for running the service.
+func (c *Config) Validate() error {
+	err := c.Kafka.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Kafka configuration")
+		return err
+	}
+	err = c.Mongo.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid MongoDB configuration")
+		return err
+	}
+	if c.Mongo.MetaCollection == "" {
+		err = errors.New("MONGO_META_COLLECTION is required")
+		err = errors.Wrap(err, "Invalid MongoDB configuration")
+		return err
+	}
+	err = c.Outbox.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid OutboxRelay configuration")
+		return err
+	}
+	err = c.Results.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Results configuration")
+		return err
+	}
+	err = c.Expiry.Validate(&c.Mongo)
+	if err != nil {
+		err = errors.Wrap(err, "Invalid ExpiryScheduler configuration")
+		return err
+	}
+	err = c.Handlers.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Handlers configuration")
+		return err
+	}
+	err = c.Authz.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Authz configuration")
+		return err
+	}
+	err = c.Approval.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid Approval configuration")
+		return err
+	}
+	err = c.Recurrence.Validate(&c.Mongo)
+	if err != nil {
+		err = errors.Wrap(err, "Invalid RecurrenceScheduler configuration")
+		return err
+	}
+	return nil
+}
+
+// Validate validates the Kafka brokers, topics, and security-configuration.
+func (k *Kafka) Validate() error {
+	if len(k.Brokers) == 0 {
+		return errors.New("KAFKA_BROKERS is required")
+	}
+	required := []struct {
+		envVar string
+		value  string
+	}{
+		{"KAFKA_CONSUMER_EVENT_GROUP", k.ConsumerEventGroup},
+		{"KAFKA_CONSUMER_EVENT_QUERY_GROUP", k.ConsumerEventQueryGroup},
+		{"KAFKA_CONSUMER_EVENT_TOPIC", k.ConsumerEventTopic},
+		{"KAFKA_CONSUMER_EVENT_QUERY_TOPIC", k.ConsumerEventQueryTopic},
+		{"KAFKA_PRODUCER_EVENT_TOPIC", k.ProducerEventTopic},
+		{"KAFKA_PRODUCER_EVENT_QUERY_TOPIC", k.ProducerEventQueryTopic},
+		{"KAFKA_PRODUCER_RESPONSE_TOPIC", k.ProducerResponseTopic},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return errors.Errorf("%s is required", r.envVar)
+		}
+	}
+	return k.ValidateSecurity()
+}
+
+// ValidateSecurity validates the TLS and SASL configuration.
+func (k *Kafka) ValidateSecurity() error {
+	t := k.TLS
+	if !t.Enabled && (t.CAFile != "" || t.CertFile != "" || t.KeyFile != "") {
+		return errors.New("Kafka TLS-files require KAFKA_TLS_ENABLED to be true")
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New(
+			"KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together",
+		)
+	}
+
+	s := k.SASL
+	if s.Mechanism == "" {
+		return nil
+	}
+	if !kafkaSASLMechanisms[strings.ToUpper(s.Mechanism)] {
+		return errors.Errorf("unsupported KAFKA_SASL_MECHANISM: %s", s.Mechanism)
+	}
+	if s.Username == "" || s.Password == "" {
+		return errors.New("SASL requires both Kafka SASL username and password")
+	}
+	return nil
+}
+
+// Validate validates the MongoDB connection-configuration.
+func (m *Mongo) Validate() error {
+	if len(m.Hosts) == 0 {
+		return errors.New("MONGO_HOSTS is required")
+	}
+	if m.Database == "" {
+		return errors.New("MONGO_DATABASE is required")
+	}
+	if m.AggCollection == "" {
+		return errors.New("MONGO_AGG_COLLECTION is required")
+	}
+	if (m.Username == "") != (m.Password == "") {
+		return errors.New("MONGO_USERNAME and MONGO_PASSWORD must be set together")
+	}
+	if m.ConnectionTimeoutMS == 0 {
+		return errors.New("MONGO_CONNECTION_TIMEOUT_MS must be greater than 0")
+	}
+	if m.ResourceTimeoutMS == 0 {
+		return errors.New("MONGO_RESOURCE_TIMEOUT_MS must be greater than 0")
+	}
+
+	switch m.SchemaValidation {
+	case "strict", "warn", "off":
+	default:
+		return errors.Errorf(
+			"invalid MONGO_SCHEMA_VALIDATION: %s, must be one of: strict, warn, off",
+			m.SchemaValidation,
+		)
+	}
+
+	tlsFiles := []struct {
+		envVar string
+		path   string
+	}{
+		{"MONGO_TLS_CA_FILE", m.TLS.CAFile},
+		{"MONGO_TLS_CERT_KEY_FILE", m.TLS.CertKeyFile},
+	}
+	for _, f := range tlsFiles {
+		if f.path == "" {
+			continue
+		}
+		if !m.TLS.Enabled {
+			return errors.Errorf("%s requires MONGO_TLS_ENABLED to be true", f.envVar)
+		}
+		_, err := os.Stat(f.path)
+		if err != nil {
+			err = errors.Wrapf(err, "%s is not readable", f.envVar)
+			return err
+		}
+	}
+
+	if m.ReadPreference != "" && !mongoReadPreferences[m.ReadPreference] {
+		return errors.Errorf("invalid MONGO_READ_PREFERENCE: %s", m.ReadPreference)
+	}
+	if m.WriteConcern != "" && m.WriteConcern != "majority" {
+		w, err := strconv.Atoi(m.WriteConcern)
+		if err != nil || w < 0 {
+			return errors.Errorf(
+				`invalid MONGO_WRITE_CONCERN: %s, must be "majority" or a number`,
+				m.WriteConcern,
+			)
+		}
+	}
+	return nil
+}
+
+// Validate validates the OutboxRelay interval and batch-size.
+func (o *Outbox) Validate() error {
+	if o.IntervalMS <= 0 {
+		return errors.New("OUTBOX_RELAY_INTERVAL_MS must be greater than 0")
+	}
+	if o.BatchSize <= 0 {
+		return errors.New("OUTBOX_RELAY_BATCH_SIZE must be greater than 0")
+	}
+	return nil
+}
+
+// Validate validates the maximum of FlashSales included in results.
+func (r *Results) Validate() error {
+	if r.MaxReturnDocuments < 0 {
+		return errors.New("FLASHSALE_MAX_RETURN_DOCUMENTS cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates the ExpiryScheduler configuration if it is enabled.
+// The DiscountCurve is validated when parsed by the ExpiryScheduler.
+func (e *Expiry) Validate(m *Mongo) error {
+	if !e.Enabled {
+		return nil
+	}
+	if m.InventoryCollection == "" {
+		return errors.New("MONGO_INVENTORY_COLLECTION is required")
+	}
+	if e.IntervalSec <= 0 {
+		return errors.New("EXPIRY_SCHEDULER_INTERVAL_SEC must be greater than 0")
+	}
+	if e.SaleDays <= 0 {
+		return errors.New("EXPIRY_SALE_DAYS must be greater than 0")
+	}
+	_, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		err = errors.Wrap(err, "invalid EXPIRY_TIME_ZONE")
+		return err
+	}
+	return nil
+}
+
+// Validate validates the configuration of middlewares around Event-handlers.
+func (h *Handlers) Validate() error {
+	if h.DedupSize < 0 {
+		return errors.New("HANDLER_DEDUP_SIZE cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates the ServiceUser, and that the roles-file is readable if
+// authorization is enabled.
+func (a *Authz) Validate() error {
+	if a.ServiceUser != "" {
+		_, err := uuuid.FromString(a.ServiceUser)
+		if err != nil {
+			err = errors.Wrap(err, "AUTHZ_SERVICE_USER is not a valid UUID")
+			return err
+		}
+	}
+	if !a.Enabled {
+		return nil
+	}
+	if a.RolesFile == "" {
+		return errors.New("AUTHZ_ROLES_FILE is required")
+	}
+	_, err := os.Stat(a.RolesFile)
+	if err != nil {
+		err = errors.Wrap(err, "AUTHZ_ROLES_FILE is not readable")
+		return err
+	}
+	return nil
+}
+
+// Validate validates the thresholds for holding FlashSales for approval.
+func (a *Approval) Validate() error {
+	if a.DiscountThreshold < 0 || a.DiscountThreshold >= 100 {
+		return errors.New("APPROVAL_DISCOUNT_THRESHOLD must be in range [0, 100)")
+	}
+	if a.WeightThreshold < 0 {
+		return errors.New("APPROVAL_WEIGHT_THRESHOLD cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates the RecurrenceScheduler configuration if it is enabled.
+func (r *Recurrence) Validate(m *Mongo) error {
+	if !r.Enabled {
+		return nil
+	}
+	if m.RecurrenceCollection == "" {
+		return errors.New("MONGO_RECURRENCE_COLLECTION is required")
+	}
+	if r.IntervalSec <= 0 {
+		return errors.New("RECURRENCE_SCHEDULER_INTERVAL_SEC must be greater than 0")
+	}
+	if r.LookaheadDays <= 0 {
+		return errors.New("RECURRENCE_LOOKAHEAD_DAYS must be greater than 0")
+	}
+	return nil
+}
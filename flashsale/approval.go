@@ -0,0 +1,446 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// reviewRequest is the Event-data for approving, rejecting, or activating a FlashSale.
+type reviewRequest struct {
+	FlashSaleID string `json:"flashSaleID"`
+}
+
+// requiresApproval checks if any item-Discount, or the total Weight of discounted
+// items in FlashSale exceeds the approval-thresholds.
+func requiresApproval(s *FlashSale, a *config.Approval) bool {
+	discountedWeight := 0.0
+	for _, item := range s.Items {
+		if a.DiscountThreshold > 0 && item.Discount > a.DiscountThreshold {
+			return true
+		}
+		if item.Discount > 0 {
+			discountedWeight += item.Weight
+		}
+	}
+	return a.WeightThreshold > 0 && discountedWeight > a.WeightThreshold
+}
+
+// holdForApproval inserts the FlashSale with StatusPendingApproval instead of
+// validating its items with the inventory. The items are validated once the
+// FlashSale is approved. The UserUUID of Event is recorded as SubmittedBy.
+func (c *ExecContext) holdForApproval(
+	collection *mongo.Collection,
+	auditColl *mongo.Collection,
+	tx *txOptions,
+	event *model.Event,
+	flashSale *FlashSale,
+) (*FlashSale, error) {
+	pendingSale := *flashSale
+	pendingSale.Status = StatusPendingApproval
+	pendingSale.SubmittedBy = event.UserUUID
+	return c.insertSale(collection, auditColl, tx, event, &pendingSale)
+}
+
+// approveFlashSale approves a FlashSale pending approval, and validates its items
+// with the inventory. The FlashSale is activated once its items are validated.
+func approveFlashSale(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return c.reviewFlashSale(collection, event, StatusApproved)
+}
+
+// rejectFlashSale rejects a FlashSale pending approval.
+func rejectFlashSale(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return c.reviewFlashSale(collection, event, StatusRejected)
+}
+
+// reviewFlashSale sets the Status of a FlashSale pending approval, and records
+// the UserUUID of reviewer. The items of approved FlashSales are validated
+// with the inventory. Users cannot review the FlashSales they submitted.
+func (c *ExecContext) reviewFlashSale(
+	collection *mongo.Collection,
+	event *model.Event,
+	status string,
+) *model.Document {
+	req := &reviewRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Review: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if req.FlashSaleID == "" {
+		err = errors.New("missing FlashSaleID")
+		err = errors.Wrap(err, "Review")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if event.UserUUID == (uuuid.UUID{}) {
+		err = errors.New("missing UserUUID of reviewer")
+		err = errors.Wrap(err, "Review")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Review: Error getting audit-collection")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	filter := map[string]interface{}{
+		"flashSaleID": req.FlashSaleID,
+		"status":      StatusPendingApproval,
+	}
+	beforeSales, err := findSalesByID(collection, filter)
+	if err != nil {
+		err = errors.Wrap(err, "Review: Error finding FlashSale to review")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if len(beforeSales) == 0 {
+		err = errors.Errorf("no FlashSale %s pending approval", req.FlashSaleID)
+		err = errors.Wrap(err, "Review")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	for _, sale := range beforeSales {
+		if sale.SubmittedBy == event.UserUUID {
+			err = errors.Errorf(
+				"user %s cannot review FlashSale %s submitted by them",
+				event.UserUUID, req.FlashSaleID,
+			)
+			err = errors.Wrap(err, "Review")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     ForbiddenError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	update := map[string]interface{}{
+		"status":     status,
+		"reviewedBy": event.UserUUID.String(),
+		"reviewedAt": time.Unix(0, event.NanoTime).Unix(),
+	}
+	var reviewedSale *FlashSale
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		_, err := tx.updateMany(collection, filter, update)
+		if err != nil {
+			err = errors.Wrap(err, "Error in UpdateMany")
+			return err
+		}
+		afterSales, err := findSalesByID(
+			collection, saleIDsFilter(beforeSales), tx.find()...,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding reviewed FlashSale")
+			return err
+		}
+		for _, sale := range afterSales {
+			reviewedSale = sale
+		}
+		err = c.writeAudit(auditColl, tx, event, beforeSales, afterSales)
+		if err != nil {
+			return err
+		}
+		if status != StatusApproved || reviewedSale == nil {
+			return nil
+		}
+		return c.publishValidation(collection, tx, event.CorrelationID, reviewedSale)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Review")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(reviewedSale)
+	if err != nil {
+		err = errors.Wrap(err, "Review: Error marshalling reviewed FlashSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// activateFlashSale activates a draft FlashSale. Drafts whose discounts exceed the
+// approval-thresholds are held for approval, same as new FlashSales. Other drafts
+// are activated by "flashSaleValidated" Event once their items are validated.
+func activateFlashSale(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	req := &reviewRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Activate: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if req.FlashSaleID == "" {
+		err = errors.New("missing FlashSaleID")
+		err = errors.Wrap(err, "Activate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Activate: Error getting audit-collection")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	filter := map[string]interface{}{
+		"flashSaleID": req.FlashSaleID,
+		"status":      StatusDraft,
+	}
+	beforeSales, err := findSalesByID(collection, filter)
+	if err != nil {
+		err = errors.Wrap(err, "Activate: Error finding FlashSale to activate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if len(beforeSales) == 0 {
+		err = errors.Errorf("no draft FlashSale %s", req.FlashSaleID)
+		err = errors.Wrap(err, "Activate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	var draftSale *FlashSale
+	for _, sale := range beforeSales {
+		draftSale = sale
+	}
+
+	var heldSale *FlashSale
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		if !requiresApproval(draftSale, &c.cfg.Approval) {
+			return c.publishValidation(collection, tx, event.CorrelationID, draftSale)
+		}
+
+		update := map[string]interface{}{
+			"status": StatusPendingApproval,
+		}
+		if event.UserUUID != (uuuid.UUID{}) {
+			update["submittedBy"] = event.UserUUID.String()
+		}
+		_, err := tx.updateMany(collection, filter, update)
+		if err != nil {
+			err = errors.Wrap(err, "Error in UpdateMany")
+			return err
+		}
+		afterSales, err := findSalesByID(
+			collection, saleIDsFilter(beforeSales), tx.find()...,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding held FlashSale")
+			return err
+		}
+		for _, sale := range afterSales {
+			heldSale = sale
+		}
+		return c.writeAudit(auditColl, tx, event, beforeSales, afterSales)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Activate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	// The response for activated FlashSales is published by "flashSaleValidated"
+	if heldSale == nil {
+		return nil
+	}
+
+	result, err := json.Marshal(heldSale)
+	if err != nil {
+		err = errors.Wrap(err, "Activate: Error marshalling held FlashSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// publishValidation publishes the Event for validating the items of a stored
+// FlashSale with the inventory. The FlashSale is activated by the resulting
+// "flashSaleValidated" Event.
+func (c *ExecContext) publishValidation(
+	collection *mongo.Collection,
+	tx *txOptions,
+	correlationID uuuid.UUID,
+	sale *FlashSale,
+) error {
+	validatedSale := *sale
+	validatedSale.ID = objectid.NilObjectID
+	validatedSale.Status = StatusActive
+	marshalSale, err := json.Marshal(&validatedSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling FlashSale for validation")
+		return err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return err
+	}
+	return c.publishEvent(
+		collection, tx, c.validationEvent(uuid, correlationID, marshalSale),
+	)
+}
@@ -0,0 +1,97 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Approval", func() {
+	var sale *FlashSale
+
+	BeforeEach(func() {
+		sale = &FlashSale{
+			Items: []SoldItem{
+				SoldItem{Weight: 40, Discount: 20},
+				SoldItem{Weight: 30, Discount: 45},
+				SoldItem{Weight: 100},
+			},
+		}
+	})
+
+	Describe("requiresApproval", func() {
+		It("should not require approval if thresholds are disabled", func() {
+			Expect(requiresApproval(sale, &config.Approval{})).To(BeFalse())
+		})
+
+		It("should require approval if any Discount exceeds threshold", func() {
+			a := &config.Approval{DiscountThreshold: 40}
+			Expect(requiresApproval(sale, a)).To(BeTrue())
+
+			a.DiscountThreshold = 45
+			Expect(requiresApproval(sale, a)).To(BeFalse())
+		})
+
+		It("should require approval if discounted Weight exceeds threshold", func() {
+			a := &config.Approval{WeightThreshold: 60}
+			Expect(requiresApproval(sale, a)).To(BeTrue())
+
+			// Items without Discount are not counted
+			a.WeightThreshold = 70
+			Expect(requiresApproval(sale, a)).To(BeFalse())
+		})
+	})
+
+	Describe("reviewFlashSale", func() {
+		It("should return error if FlashSaleID is missing", func() {
+			c := NewExecContext(config.Default())
+			doc := approveFlashSale(c, nil, &model.Event{
+				EventAction:   "update",
+				ServiceAction: "approveFlashSale",
+				Data:          []byte(`{}`),
+			})
+			Expect(doc.Error).To(ContainSubstring("missing FlashSaleID"))
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+		})
+
+		It("should return error if UserUUID of reviewer is missing", func() {
+			c := NewExecContext(config.Default())
+			doc := rejectFlashSale(c, nil, &model.Event{
+				EventAction:   "update",
+				ServiceAction: "rejectFlashSale",
+				Data:          []byte(`{"flashSaleID": "abc"}`),
+			})
+			Expect(doc.Error).To(ContainSubstring("missing UserUUID"))
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+		})
+	})
+
+	Describe("activateFlashSale", func() {
+		It("should return error if FlashSaleID is missing", func() {
+			c := NewExecContext(config.Default())
+			doc := activateFlashSale(c, nil, &model.Event{
+				EventAction:   "update",
+				ServiceAction: "activateFlashSale",
+				Data:          []byte(`{}`),
+			})
+			Expect(doc.Error).To(ContainSubstring("missing FlashSaleID"))
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+		})
+	})
+
+	It("should not allow updates to set the approval-fields", func() {
+		c := NewExecContext(config.Default())
+		for _, field := range approvalFields {
+			doc := c.update(nil, &model.Event{
+				EventAction: "update",
+				Data: []byte(`{
+					"filter": {"flashSaleID": "abc"},
+					"update": {"` + field + `": "active"}
+				}`),
+			})
+			Expect(doc.Error).To(ContainSubstring(field + " cannot be updated"))
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+		}
+	})
+})
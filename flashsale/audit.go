@@ -0,0 +1,296 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// AuditRecord is a change made to a FlashSale by an Event.
+// Before is nil for inserted FlashSales, and After is nil for deleted FlashSales.
+type AuditRecord struct {
+	ID            objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	FlashSaleID   uuuid.UUID        `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
+	EventAction   string            `bson:"eventAction,omitempty" json:"eventAction,omitempty"`
+	ServiceAction string            `bson:"serviceAction,omitempty" json:"serviceAction,omitempty"`
+	Before        *FlashSale        `bson:"before,omitempty" json:"before,omitempty"`
+	After         *FlashSale        `bson:"after,omitempty" json:"after,omitempty"`
+	UserUUID      uuuid.UUID        `bson:"userUUID,omitempty" json:"userUUID,omitempty"`
+	CorrelationID uuuid.UUID        `bson:"correlationID,omitempty" json:"correlationID,omitempty"`
+	EventUUID     uuuid.UUID        `bson:"eventUUID,omitempty" json:"eventUUID,omitempty"`
+	Timestamp     int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+}
+
+// Same reasons as flashSaleBSON. Before and After are the documents from the
+// FlashSale's own BSON conversion, since nested FlashSales are not marshalled
+// using FlashSale#MarshalBSON.
+type auditRecordBSON struct {
+	ID            objectid.ObjectID `bson:"_id,omitempty"`
+	FlashSaleID   string            `bson:"flashSaleID,omitempty"`
+	EventAction   string            `bson:"eventAction,omitempty"`
+	ServiceAction string            `bson:"serviceAction,omitempty"`
+	Before        *bson.Document    `bson:"before,omitempty"`
+	After         *bson.Document    `bson:"after,omitempty"`
+	UserUUID      string            `bson:"userUUID,omitempty"`
+	CorrelationID string            `bson:"correlationID,omitempty"`
+	EventUUID     string            `bson:"eventUUID,omitempty"`
+	Timestamp     int64             `bson:"timestamp,omitempty"`
+}
+
+// auditCollection returns the collection with provided name storing AuditRecords.
+func auditCollection(
+	aggCollection *mongo.Collection,
+	name string,
+) (*mongo.Collection, error) {
+	indexConfigs := []mongo.IndexConfig{
+		mongo.IndexConfig{
+			ColumnConfig: []mongo.IndexColumnConfig{
+				mongo.IndexColumnConfig{
+					Name: "flashSaleID",
+				},
+				mongo.IndexColumnConfig{
+					Name: "timestamp",
+				},
+			},
+			Name: "flashSaleID_timestamp_index",
+		},
+	}
+	return siblingCollection(aggCollection, name, &AuditRecord{}, indexConfigs)
+}
+
+// writeAudit inserts an AuditRecord for each changed FlashSale.
+// The before and after maps are keyed by FlashSale ObjectIDs.
+// No AuditRecords are written in replay, since those already exist.
+func (c *ExecContext) writeAudit(
+	auditColl *mongo.Collection,
+	tx *txOptions,
+	event *model.Event,
+	before map[objectid.ObjectID]*FlashSale,
+	after map[objectid.ObjectID]*FlashSale,
+) error {
+	if c.Replay {
+		return nil
+	}
+
+	ids := make([]objectid.ObjectID, 0)
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if before[id] == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	timestamp := time.Now().Unix()
+	for _, id := range ids {
+		record := &AuditRecord{
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			Before:        before[id],
+			After:         after[id],
+			UserUUID:      event.UserUUID,
+			CorrelationID: event.CorrelationID,
+			EventUUID:     event.UUID,
+			Timestamp:     timestamp,
+		}
+		if record.After != nil {
+			record.FlashSaleID = record.After.FlashSaleID
+		} else {
+			record.FlashSaleID = record.Before.FlashSaleID
+		}
+
+		_, err := tx.insertOne(auditColl, record)
+		if err != nil {
+			err = errors.Wrapf(err, "Error inserting AuditRecord for %s", id.Hex())
+			return err
+		}
+	}
+	return nil
+}
+
+// findSalesByID returns the FlashSales matching the filter, keyed by their ObjectIDs.
+func findSalesByID(
+	collection *mongo.Collection,
+	filter map[string]interface{},
+	opts ...findopt.Find,
+) (map[objectid.ObjectID]*FlashSale, error) {
+	findResults, err := collection.Find(filter, opts...)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding FlashSales")
+		return nil, err
+	}
+	sales := map[objectid.ObjectID]*FlashSale{}
+	for _, r := range findResults {
+		sale, assertOK := r.(*FlashSale)
+		if !assertOK {
+			return nil, errors.New("error asserting find-result to FlashSale")
+		}
+		sales[sale.ID] = sale
+	}
+	return sales, nil
+}
+
+// saleIDsFilter returns a filter matching the FlashSales with provided ObjectIDs.
+func saleIDsFilter(sales map[objectid.ObjectID]*FlashSale) map[string]interface{} {
+	ids := make([]objectid.ObjectID, 0)
+	for id := range sales {
+		ids = append(ids, id)
+	}
+	return map[string]interface{}{
+		"_id": map[string]interface{}{
+			"$in": ids,
+		},
+	}
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (r AuditRecord) MarshalBSON() ([]byte, error) {
+	before, err := saleDocument(r.Before)
+	if err != nil {
+		err = errors.Wrap(err, "MarshalBSON Error: Error marshalling Before")
+		return nil, err
+	}
+	after, err := saleDocument(r.After)
+	if err != nil {
+		err = errors.Wrap(err, "MarshalBSON Error: Error marshalling After")
+		return nil, err
+	}
+
+	rb := &auditRecordBSON{
+		ID:            r.ID,
+		EventAction:   r.EventAction,
+		ServiceAction: r.ServiceAction,
+		Before:        before,
+		After:         after,
+		Timestamp:     r.Timestamp,
+	}
+	if r.FlashSaleID != (uuuid.UUID{}) {
+		rb.FlashSaleID = r.FlashSaleID.String()
+	}
+	if r.UserUUID != (uuuid.UUID{}) {
+		rb.UserUUID = r.UserUUID.String()
+	}
+	if r.CorrelationID != (uuuid.UUID{}) {
+		rb.CorrelationID = r.CorrelationID.String()
+	}
+	if r.EventUUID != (uuuid.UUID{}) {
+		rb.EventUUID = r.EventUUID.String()
+	}
+	return bson.Marshal(rb)
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (r *AuditRecord) MarshalJSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"eventAction":   r.EventAction,
+		"serviceAction": r.ServiceAction,
+		"timestamp":     r.Timestamp,
+	}
+	if r.ID != objectid.NilObjectID {
+		in["_id"] = r.ID.Hex()
+	}
+	if r.FlashSaleID != (uuuid.UUID{}) {
+		in["flashSaleID"] = r.FlashSaleID.String()
+	}
+	if r.Before != nil {
+		in["before"] = r.Before
+	}
+	if r.After != nil {
+		in["after"] = r.After
+	}
+	if r.UserUUID != (uuuid.UUID{}) {
+		in["userUUID"] = r.UserUUID.String()
+	}
+	if r.CorrelationID != (uuuid.UUID{}) {
+		in["correlationID"] = r.CorrelationID.String()
+	}
+	if r.EventUUID != (uuuid.UUID{}) {
+		in["eventUUID"] = r.EventUUID.String()
+	}
+	return json.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (r *AuditRecord) UnmarshalBSON(in []byte) error {
+	rb := &auditRecordBSON{}
+	err := bson.Unmarshal(in, rb)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error")
+		return err
+	}
+
+	r.ID = rb.ID
+	r.EventAction = rb.EventAction
+	r.ServiceAction = rb.ServiceAction
+	r.Timestamp = rb.Timestamp
+
+	r.Before, err = saleFromDocument(rb.Before)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing Before")
+		return err
+	}
+	r.After, err = saleFromDocument(rb.After)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing After")
+		return err
+	}
+
+	uuids := []struct {
+		value string
+		dest  *uuuid.UUID
+	}{
+		{rb.FlashSaleID, &r.FlashSaleID},
+		{rb.UserUUID, &r.UserUUID},
+		{rb.CorrelationID, &r.CorrelationID},
+		{rb.EventUUID, &r.EventUUID},
+	}
+	for _, u := range uuids {
+		if u.value == "" {
+			continue
+		}
+		*u.dest, err = uuuid.FromString(u.value)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing UUID")
+			return err
+		}
+	}
+	return nil
+}
+
+// saleDocument converts the FlashSale to a BSON document using FlashSale#MarshalBSON.
+func saleDocument(s *FlashSale) (*bson.Document, error) {
+	if s == nil {
+		return nil, nil
+	}
+	saleBytes, err := s.MarshalBSON()
+	if err != nil {
+		return nil, err
+	}
+	return bson.ReadDocument(saleBytes)
+}
+
+// saleFromDocument converts the BSON document to a FlashSale using
+// FlashSale#UnmarshalBSON.
+func saleFromDocument(doc *bson.Document) (*FlashSale, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	saleBytes, err := doc.MarshalBSON()
+	if err != nil {
+		return nil, err
+	}
+	sale := &FlashSale{}
+	err = sale.UnmarshalBSON(saleBytes)
+	if err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
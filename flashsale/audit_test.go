@@ -0,0 +1,98 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuditRecord", func() {
+	It("should keep all FlashSale fields in BSON", func() {
+		flashSaleID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		reviewerID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		submitterID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		before := &FlashSale{
+			ID:          objectid.New(),
+			FlashSaleID: flashSaleID,
+			Items: []SoldItem{
+				SoldItem{
+					ItemID: itemID,
+					Weight: 12.24,
+					Lot:    "test-lot",
+				},
+			},
+			StartTime:   1541250000,
+			EndTime:     1541253600,
+			Status:      StatusPendingApproval,
+			Timestamp:   1541240000,
+			SubmittedBy: submitterID,
+			Region:      "test-region",
+			StoreIDs:    []string{"test-store-1", "test-store-2"},
+			TimeZone:    "America/Toronto",
+		}
+		after := *before
+		after.Status = StatusApproved
+		after.ReviewedBy = reviewerID
+		after.ReviewedAt = 1541245000
+
+		record := &AuditRecord{
+			ID:            objectid.New(),
+			FlashSaleID:   flashSaleID,
+			EventAction:   "update",
+			ServiceAction: "flashSaleApproved",
+			Before:        before,
+			After:         &after,
+			EventUUID:     eventUUID,
+			Timestamp:     1541245000,
+		}
+		recordBytes, err := record.MarshalBSON()
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalled := &AuditRecord{}
+		err = unmarshalled.UnmarshalBSON(recordBytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalled).To(Equal(record))
+	})
+
+	It("should keep nil Before for inserted FlashSales", func() {
+		flashSaleID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		record := &AuditRecord{
+			FlashSaleID: flashSaleID,
+			EventAction: "insert",
+			After: &FlashSale{
+				FlashSaleID: flashSaleID,
+				Items: []SoldItem{
+					SoldItem{
+						ItemID: itemID,
+						Weight: 3.5,
+						Lot:    "test-lot",
+					},
+				},
+				Status:    StatusActive,
+				Timestamp: 1541240000,
+			},
+			Timestamp: 1541240000,
+		}
+		recordBytes, err := record.MarshalBSON()
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalled := &AuditRecord{}
+		err = unmarshalled.UnmarshalBSON(recordBytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalled.Before).To(BeNil())
+		Expect(unmarshalled.After).To(Equal(record.After))
+	})
+})
@@ -0,0 +1,239 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RoleMerchandiser is the role of users who create and manage FlashSales.
+const RoleMerchandiser = "merchandiser"
+
+// RoleMerchandisingManager is the role of users who can additionally delete
+// FlashSales and change their prices.
+const RoleMerchandisingManager = "merchandisingManager"
+
+// RoleService is the role of other services, such as the inventory, which
+// respond to the Events of FlashSale Aggregate.
+const RoleService = "service"
+
+// RoleResolver resolves the roles of a user.
+type RoleResolver interface {
+	Roles(userUUID uuuid.UUID) ([]string, error)
+}
+
+// StaticRoleResolver resolves the roles of users from a YAML file mapping
+// the UserUUIDs to their roles, such as:
+//
+//	6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e:
+//	  - merchandisingManager
+//
+// The file is read once when the StaticRoleResolver is created.
+type StaticRoleResolver struct {
+	roles map[uuuid.UUID][]string
+}
+
+// NewStaticRoleResolver creates a StaticRoleResolver from the roles-file.
+func NewStaticRoleResolver(path string) (*StaticRoleResolver, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = errors.Wrapf(err, "Error reading roles-file %s", path)
+		return nil, err
+	}
+	userRoles := map[string][]string{}
+	err = yaml.UnmarshalStrict(data, &userRoles)
+	if err != nil {
+		err = errors.Wrapf(err, "Error parsing roles-file %s", path)
+		return nil, err
+	}
+
+	r := &StaticRoleResolver{
+		roles: map[uuuid.UUID][]string{},
+	}
+	for user, roles := range userRoles {
+		userUUID, err := uuuid.FromString(user)
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing UserUUID %s in roles-file", user)
+			return nil, err
+		}
+		r.roles[userUUID] = roles
+	}
+	return r, nil
+}
+
+// Roles returns the roles of user. Unknown users have no roles.
+func (r *StaticRoleResolver) Roles(userUUID uuuid.UUID) ([]string, error) {
+	return r.roles[userUUID], nil
+}
+
+// Policy defines the roles allowed to run each action.
+type Policy struct {
+	// Actions are the roles allowed to run the actions.
+	// Actions which are not listed are allowed for all users.
+	Actions map[HandlerKey][]string
+	// PriceChange are the roles allowed to run "update" Events which change
+	// the prices or discounts of FlashSales, in addition to Actions.
+	PriceChange []string
+}
+
+// DefaultPolicy returns the Policy allowing merchandisers to create and update
+// FlashSales, and only merchandising-managers to delete, approve or reject them,
+// or change prices.
+// The Events from other services, such as "flashSaleValidated", are only allowed
+// for services. The "flashSaleValidated" Events carry the service-user from the
+// validation-Event, which must have the service role. Queries are allowed for
+// all users.
+func DefaultPolicy() *Policy {
+	editors := []string{RoleMerchandiser, RoleMerchandisingManager}
+	managers := []string{RoleMerchandisingManager}
+	services := []string{RoleService}
+	// The schedulers create FlashSales as the service
+	creators := []string{RoleMerchandiser, RoleMerchandisingManager, RoleService}
+	return &Policy{
+		Actions: map[HandlerKey][]string{
+			HandlerKey{"insert", ""}:                                editors,
+			HandlerKey{"insert", "flashSaleCreated"}:                creators,
+			HandlerKey{"insert", "flashSaleValidated"}:              services,
+			HandlerKey{"insert", "flashSaleFromTemplate"}:           editors,
+			HandlerKey{"insert", "flashSaleCloned"}:                 editors,
+			HandlerKey{"insert", "templateCreated"}:                 editors,
+			HandlerKey{"insert", "recurringSaleCreated"}:            editors,
+			HandlerKey{"update", ""}:                                editors,
+			HandlerKey{"update", "activateFlashSale"}:               editors,
+			HandlerKey{"update", "approveFlashSale"}:                managers,
+			HandlerKey{"update", "rejectFlashSale"}:                 managers,
+			HandlerKey{"update", "recurringSaleOccurrenceSkipped"}:  editors,
+			HandlerKey{"update", "recurringSaleOccurrenceModified"}: editors,
+			HandlerKey{"delete", ""}:                                managers,
+		},
+		PriceChange: managers,
+	}
+}
+
+// requiredRoles returns the roles of which a user must have at least one
+// for running the Event. No roles are returned if the Event is allowed
+// for all users.
+func (p *Policy) requiredRoles(event *model.Event) [][]string {
+	required := [][]string{}
+	roles, ok := p.Actions[HandlerKey{
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+	}]
+	if ok {
+		required = append(required, roles)
+	}
+	if event.EventAction == "update" && changesPrices(event.Data) {
+		required = append(required, p.PriceChange)
+	}
+	if event.ServiceAction == "recurringSaleOccurrenceModified" &&
+		changesOccurrenceItems(event.Data) {
+		required = append(required, p.PriceChange)
+	}
+	return required
+}
+
+// changesPrices returns true if the "update" Event-data sets the items, or any
+// field of items, such as their prices or discounts.
+func changesPrices(data []byte) bool {
+	flashSaleUpdate := &flashSaleUpdate{}
+	err := json.Unmarshal(data, flashSaleUpdate)
+	if err != nil {
+		// Invalid updates are rejected by the Handler
+		return false
+	}
+	for key := range flashSaleUpdate.Update {
+		if strings.HasPrefix(key, "items") {
+			return true
+		}
+	}
+	return false
+}
+
+// changesOccurrenceItems returns true if the Event-data modifying an occurrence
+// of RecurringSale sets its items.
+func changesOccurrenceItems(data []byte) bool {
+	req := &occurrenceRequest{}
+	err := json.Unmarshal(data, req)
+	if err != nil {
+		// Invalid requests are rejected by the Handler
+		return false
+	}
+	return len(req.Items) > 0
+}
+
+// Authorize only allows the users with roles required by Policy to run the
+// Events. A ForbiddenError Document is returned for the Events which the
+// user is not allowed to run.
+func Authorize(resolver RoleResolver, policy *Policy) Middleware {
+	return func(next Handler) Handler {
+		return func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			required := policy.requiredRoles(event)
+			if len(required) == 0 {
+				return next(c, collection, event)
+			}
+
+			roles, err := resolver.Roles(event.UserUUID)
+			if err != nil {
+				err = errors.Wrapf(
+					err, "Error resolving roles of user %s", event.UserUUID,
+				)
+				err = errors.Wrap(err, "Authorize")
+				log.Println(err)
+				return &model.Document{
+					AggregateID:   event.AggregateID,
+					CorrelationID: event.CorrelationID,
+					Error:         err.Error(),
+					ErrorCode:     InternalError,
+					EventAction:   event.EventAction,
+					ServiceAction: event.ServiceAction,
+					UUID:          event.UUID,
+				}
+			}
+
+			for _, r := range required {
+				if !hasAnyRole(roles, r) {
+					err = errors.Errorf(
+						"user %s is not allowed to run EventAction: %s, "+
+							"ServiceAction: %s, requires one of roles: %s",
+						event.UserUUID, event.EventAction, event.ServiceAction,
+						strings.Join(r, ", "),
+					)
+					err = errors.Wrap(err, "Authorize")
+					log.Println(err)
+					return &model.Document{
+						AggregateID:   event.AggregateID,
+						CorrelationID: event.CorrelationID,
+						Error:         err.Error(),
+						ErrorCode:     ForbiddenError,
+						EventAction:   event.EventAction,
+						ServiceAction: event.ServiceAction,
+						UUID:          event.UUID,
+					}
+				}
+			}
+			return next(c, collection, event)
+		}
+	}
+}
+
+func hasAnyRole(roles []string, allowed []string) bool {
+	for _, role := range roles {
+		for _, a := range allowed {
+			if role == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package flashsale
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// mockRoleResolver resolves the same roles for all users.
+type mockRoleResolver struct {
+	roles []string
+	err   error
+}
+
+func (r *mockRoleResolver) Roles(userUUID uuuid.UUID) ([]string, error) {
+	return r.roles, r.err
+}
+
+var _ = Describe("Authorize", func() {
+	var (
+		handled bool
+		handler Handler
+	)
+
+	// authorize runs the Event through Authorize-middleware with the roles.
+	authorize := func(roles []string, event *model.Event) *model.Document {
+		resolver := &mockRoleResolver{roles: roles}
+		return Authorize(resolver, DefaultPolicy())(handler)(nil, nil, event)
+	}
+
+	BeforeEach(func() {
+		handled = false
+		handler = func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			handled = true
+			return nil
+		}
+	})
+
+	It("should allow actions not listed in Policy for all users", func() {
+		doc := authorize(nil, &model.Event{
+			EventAction:   "query",
+			ServiceAction: "flashSaleByID",
+		})
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should allow users with required roles", func() {
+		doc := authorize([]string{RoleMerchandiser}, &model.Event{
+			EventAction: "insert",
+		})
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should return ForbiddenError for users without required roles", func() {
+		doc := authorize([]string{RoleMerchandiser}, &model.Event{
+			EventAction: "delete",
+		})
+		Expect(handled).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(ForbiddenError)))
+
+		doc = authorize([]string{RoleMerchandisingManager}, &model.Event{
+			EventAction: "delete",
+		})
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should only allow merchandising-managers to change prices", func() {
+		event := &model.Event{
+			EventAction: "update",
+			Data: []byte(`{
+				"filter": {"flashSaleID": "abc"},
+				"update": {"items.0.price": 12.5}
+			}`),
+		}
+		doc := authorize([]string{RoleMerchandiser}, event)
+		Expect(handled).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(ForbiddenError)))
+
+		doc = authorize([]string{RoleMerchandisingManager}, event)
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should treat changing any field of items as a price change", func() {
+		keys := []string{"items", "items.0", "items.0.weight", "items.$.lot"}
+		for _, key := range keys {
+			event := &model.Event{
+				EventAction: "update",
+				Data: []byte(`{
+					"filter": {"flashSaleID": "abc"},
+					"update": {"` + key + `": 10}
+				}`),
+			}
+			doc := authorize([]string{RoleMerchandiser}, event)
+			Expect(doc.ErrorCode).To(Equal(int16(ForbiddenError)))
+		}
+		Expect(handled).To(BeFalse())
+	})
+
+	It("should only allow services to run the Events from other services", func() {
+		event := &model.Event{
+			EventAction:   "insert",
+			ServiceAction: "flashSaleValidated",
+		}
+		doc := authorize([]string{RoleMerchandisingManager}, event)
+		Expect(handled).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(ForbiddenError)))
+
+		doc = authorize([]string{RoleService}, event)
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should only allow merchandising-managers to change items of occurrence", func() {
+		event := &model.Event{
+			EventAction:   "update",
+			ServiceAction: "recurringSaleOccurrenceModified",
+			Data: []byte(`{
+				"recurrenceID": "abc",
+				"occurrence": "2018-11-09T15:00:00",
+				"items": [{"itemID": "def", "weight": 10, "price": 2.5}]
+			}`),
+		}
+		doc := authorize([]string{RoleMerchandiser}, event)
+		Expect(handled).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(ForbiddenError)))
+
+		event.Data = []byte(`{
+			"recurrenceID": "abc",
+			"occurrence": "2018-11-09T15:00:00",
+			"start": "2018-11-09T16:00:00"
+		}`)
+		doc = authorize([]string{RoleMerchandiser}, event)
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should allow merchandisers to update other fields", func() {
+		doc := authorize([]string{RoleMerchandiser}, &model.Event{
+			EventAction: "update",
+			Data: []byte(`{
+				"filter": {"flashSaleID": "abc"},
+				"update": {"endTime": 1546300800}
+			}`),
+		})
+		Expect(doc).To(BeNil())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should return InternalError if roles cannot be resolved", func() {
+		resolver := &mockRoleResolver{err: errors.New("some-error")}
+		doc := Authorize(resolver, DefaultPolicy())(handler)(nil, nil, &model.Event{
+			EventAction: "insert",
+		})
+		Expect(handled).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+	})
+
+	Describe("StaticRoleResolver", func() {
+		var rolesFile string
+
+		writeRoles := func(content string) {
+			f, err := ioutil.TempFile("", "roles")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = f.WriteString(content)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+			rolesFile = f.Name()
+		}
+
+		AfterEach(func() {
+			os.Remove(rolesFile)
+		})
+
+		It("should resolve roles of users in roles-file", func() {
+			userUUID, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			writeRoles(userUUID.String() + ":\n  - merchandisingManager\n")
+
+			r, err := NewStaticRoleResolver(rolesFile)
+			Expect(err).ToNot(HaveOccurred())
+			roles, err := r.Roles(userUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(roles).To(Equal([]string{RoleMerchandisingManager}))
+		})
+
+		It("should return error for invalid UserUUIDs", func() {
+			writeRoles("some-user:\n  - merchandiser\n")
+			_, err := NewStaticRoleResolver(rolesFile)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
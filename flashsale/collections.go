@@ -0,0 +1,50 @@
+package flashsale
+
+import (
+	"sync"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+var (
+	siblingCollections     = map[string]*mongo.Collection{}
+	siblingCollectionsLock sync.Mutex
+)
+
+// siblingCollection returns the collection with provided name from the same
+// database as the aggregate-collection. The collection is created if it
+// doesn't exist, and is reused for subsequent calls.
+func siblingCollection(
+	aggCollection *mongo.Collection,
+	name string,
+	schemaStruct interface{},
+	indexes []mongo.IndexConfig,
+) (*mongo.Collection, error) {
+	if aggCollection == nil {
+		return nil, errors.New("aggregate-collection cannot be nil")
+	}
+
+	siblingCollectionsLock.Lock()
+	defer siblingCollectionsLock.Unlock()
+
+	key := aggCollection.Database + "." + name
+	if coll, exists := siblingCollections[key]; exists {
+		return coll, nil
+	}
+
+	c := &mongo.Collection{
+		Connection:   aggCollection.Connection,
+		Database:     aggCollection.Database,
+		Name:         name,
+		SchemaStruct: schemaStruct,
+		Indexes:      indexes,
+	}
+	coll, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrapf(err, "Error creating MongoCollection: %s", name)
+		return nil, err
+	}
+	siblingCollections[key] = coll
+	return coll, nil
+}
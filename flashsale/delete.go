@@ -6,15 +6,21 @@ import (
 
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
 	"github.com/pkg/errors"
 )
 
 type deleteResult struct {
-	DeletedCount int64 `json:"deletedCount,omitempty"`
+	DeletedCount int64        `json:"deletedCount,omitempty"`
+	FlashSales   []*FlashSale `json:"flashSales,omitempty"`
+	// Truncated is true if the FlashSales exceeded the maximum returned FlashSales.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
-// Delete handles "delete" events.
-func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
+func (c *ExecContext) delete(
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
 	filter := map[string]interface{}{}
 
 	err := json.Unmarshal(event.Data, &filter)
@@ -32,6 +38,10 @@ func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
+	// The Event-data is the filter itself, so the option is removed from it
+	returnDocuments, _ := filter[returnDocumentsKey].(bool)
+	delete(filter, returnDocumentsKey)
+
 	if len(filter) == 0 {
 		err = errors.New("blank filter provided")
 		err = errors.Wrap(err, "Delete")
@@ -47,9 +57,9 @@ func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
-	deleteStats, err := collection.DeleteMany(filter)
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
 	if err != nil {
-		err = errors.Wrap(err, "Delete: Error in DeleteMany")
+		err = errors.Wrap(err, "Delete: Error getting audit-collection")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -62,7 +72,41 @@ func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
-	result := &deleteResult{deleteStats.DeletedCount}
+	result := &deleteResult{}
+	var beforeSales map[objectid.ObjectID]*FlashSale
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		// The FlashSales are found before deleting for auditing
+		beforeSales, err = findSalesByID(collection, filter, tx.find()...)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding FlashSales to delete")
+			return err
+		}
+		deleteStats, err := tx.deleteMany(collection, filter)
+		if err != nil {
+			err = errors.Wrap(err, "Error in DeleteMany")
+			return err
+		}
+		result.DeletedCount = deleteStats.DeletedCount
+		return c.writeAudit(auditColl, tx, event, beforeSales, nil)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Delete")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if returnDocuments {
+		result.FlashSales, result.Truncated = returnedSales(
+			beforeSales, c.cfg.Results.MaxReturnDocuments,
+		)
+	}
 	resultMarshal, err := json.Marshal(result)
 	if err != nil {
 		err = errors.Wrap(err, "Delete: Error marshalling FlashSale Delete-result")
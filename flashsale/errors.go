@@ -6,3 +6,15 @@ const InternalError = 2
 // DatabaseError is when some operation related to Database, such as insert or find,
 // goes wrong and the task cannot proceed.
 const DatabaseError = 3
+
+// SaleConflictError is when a FlashSale contains an item-lot which is already part of
+// another FlashSale during an overlapping time-window.
+const SaleConflictError = 4
+
+// UnsupportedActionError is when no handler is registered for the EventAction
+// and ServiceAction of an Event.
+const UnsupportedActionError = 5
+
+// ForbiddenError is when the user is not allowed to run the EventAction and
+// ServiceAction of an Event.
+const ForbiddenError = 6
@@ -0,0 +1,191 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// Emission is an Event or a response-Document produced by the Event-handlers.
+// Only one of Event and Document is set.
+type Emission struct {
+	Topic    string          `json:"topic,omitempty"`
+	Event    *model.Event    `json:"event,omitempty"`
+	Document *model.Document `json:"document,omitempty"`
+}
+
+// ExecContext is the context in which the Event-handlers are executed.
+// In live-processing, the handlers produce Events and response-Documents.
+// In replay, the handlers only apply the state-changes, and the Events and
+// response-Documents which would have been produced are recorded as Emissions.
+type ExecContext struct {
+	Replay bool
+
+	cfg             *config.Config
+	registry        *Registry
+	middlewaresLock sync.RWMutex
+	middlewares     []Middleware
+	emittedLock     sync.Mutex
+	emitted         []Emission
+}
+
+// NewExecContext creates an ExecContext for live-processing of Events,
+// using the DefaultRegistry.
+func NewExecContext(cfg *config.Config) *ExecContext {
+	return &ExecContext{
+		cfg:      cfg,
+		registry: DefaultRegistry(),
+	}
+}
+
+// NewReplayContext creates an ExecContext for replaying previously processed Events.
+func NewReplayContext(cfg *config.Config) *ExecContext {
+	return &ExecContext{
+		Replay:   true,
+		cfg:      cfg,
+		registry: DefaultRegistry(),
+		emitted:  []Emission{},
+	}
+}
+
+// Emitted returns the Emissions recorded in replay.
+func (c *ExecContext) Emitted() []Emission {
+	c.emittedLock.Lock()
+	defer c.emittedLock.Unlock()
+	return append([]Emission{}, c.emitted...)
+}
+
+func (c *ExecContext) record(emission Emission) {
+	c.emittedLock.Lock()
+	c.emitted = append(c.emitted, emission)
+	c.emittedLock.Unlock()
+}
+
+// Registry returns the Registry the Events are routed with.
+func (c *ExecContext) Registry() *Registry {
+	return c.registry
+}
+
+// serviceUser returns the UserUUID for the Events created by the service itself.
+// A blank UserUUID is returned if none is configured.
+func (c *ExecContext) serviceUser() uuuid.UUID {
+	// The UserUUID is checked when validating the config
+	userUUID, err := uuuid.FromString(c.cfg.Authz.ServiceUser)
+	if err != nil {
+		return uuuid.UUID{}
+	}
+	return userUUID
+}
+
+// Handle runs the Handler registered for the Event, wrapped in the Middlewares
+// added with Use, and returns the response-Document to be produced, if any.
+func (c *ExecContext) Handle(
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return c.respond(c.handler()(c, collection, event))
+}
+
+// respond returns the response-Document to be produced. No Documents are
+// produced in replay, and the Document is recorded instead.
+func (c *ExecContext) respond(doc *model.Document) *model.Document {
+	if !c.Replay || doc == nil {
+		return doc
+	}
+	c.record(Emission{
+		Document: doc,
+	})
+	return nil
+}
+
+// publishEvent writes the Event to outbox for publishing on the event-topic.
+// The Event is recorded instead in replay.
+func (c *ExecContext) publishEvent(
+	collection *mongo.Collection,
+	tx *txOptions,
+	event *model.Event,
+) error {
+	topic := c.cfg.Kafka.ProducerEventTopic
+	if c.Replay {
+		c.record(Emission{
+			Topic: topic,
+			Event: event,
+		})
+		return nil
+	}
+
+	marshalEvent, err := json.Marshal(event)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling Event")
+		return err
+	}
+	return c.writeOutbox(collection, tx, topic, marshalEvent)
+}
+
+// publishCreatedSale publishes a "flashSaleCreated" Event for the FlashSale as the
+// service-user. The Event is stored in the event-store, and the FlashSale is
+// created when the Event is polled, so the FlashSale is also created in rebuilds.
+func (c *ExecContext) publishCreatedSale(
+	collection *mongo.Collection,
+	flashSale *FlashSale,
+	now time.Time,
+) error {
+	marshalSale, err := json.Marshal(flashSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling FlashSale")
+		return err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return err
+	}
+	cid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return err
+	}
+
+	return c.publishEvent(collection, nil, &model.Event{
+		AggregateID:   AggregateID,
+		CorrelationID: cid,
+		Data:          marshalSale,
+		EventAction:   "insert",
+		NanoTime:      now.UnixNano(),
+		ServiceAction: "flashSaleCreated",
+		UserUUID:      c.serviceUser(),
+		UUID:          uuid,
+		YearBucket:    int16(now.Year()),
+	})
+}
+
+// publishDocument writes the response-Document to outbox for publishing on the
+// response-topic, so the response is published if the state-changes are applied.
+// The Document is recorded instead in replay.
+func (c *ExecContext) publishDocument(
+	collection *mongo.Collection,
+	tx *txOptions,
+	doc *model.Document,
+) error {
+	topic := c.cfg.Kafka.ProducerResponseTopic
+	if c.Replay {
+		c.record(Emission{
+			Topic:    topic,
+			Document: doc,
+		})
+		return nil
+	}
+
+	marshalDoc, err := json.Marshal(doc)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling Document")
+		return err
+	}
+	return c.writeOutbox(collection, tx, topic, marshalDoc)
+}
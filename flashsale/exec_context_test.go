@@ -0,0 +1,112 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExecContext", func() {
+	var doc *model.Document
+
+	BeforeEach(func() {
+		doc = &model.Document{
+			AggregateID:   AggregateID,
+			EventAction:   "insert",
+			ServiceAction: "flashSaleCreated",
+		}
+	})
+
+	It("should return response-Documents in live-processing", func() {
+		c := NewExecContext(config.Default())
+		Expect(c.respond(doc)).To(Equal(doc))
+		Expect(c.Emitted()).To(BeEmpty())
+	})
+
+	It("should record response-Documents instead of returning them in replay", func() {
+		c := NewReplayContext(config.Default())
+		Expect(c.respond(doc)).To(BeNil())
+		Expect(c.Emitted()).To(Equal([]Emission{
+			Emission{Document: doc},
+		}))
+	})
+
+	It("should record Events instead of publishing them in replay", func() {
+		c := NewReplayContext(config.Default())
+		event := &model.Event{
+			AggregateID:   2,
+			EventAction:   "update",
+			ServiceAction: "createFlashSale",
+		}
+		err := c.publishEvent(nil, nil, event)
+		Expect(err).ToNot(HaveOccurred())
+
+		emitted := c.Emitted()
+		Expect(emitted).To(HaveLen(1))
+		Expect(emitted[0].Event).To(Equal(event))
+		Expect(emitted[0].Document).To(BeNil())
+	})
+
+	It("should record published Documents instead of writing them in replay", func() {
+		c := NewReplayContext(config.Default())
+		err := c.publishDocument(nil, nil, doc)
+		Expect(err).ToNot(HaveOccurred())
+
+		emitted := c.Emitted()
+		Expect(emitted).To(HaveLen(1))
+		Expect(emitted[0].Document).To(Equal(doc))
+		Expect(emitted[0].Event).To(BeNil())
+	})
+
+	It("should publish created FlashSales as Events of the service-user", func() {
+		cfg := config.Default()
+		cfg.Kafka.ProducerEventTopic = "event.rns_eventstore.events"
+		cfg.Authz.ServiceUser = "2c6f1b8e-4a3d-4e5f-9b7a-1d0c8e6f4a21"
+		c := NewReplayContext(cfg)
+
+		flashSaleID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		flashSale := &FlashSale{
+			FlashSaleID: flashSaleID,
+			Status:      StatusDraft,
+		}
+		now := time.Now()
+		err = c.publishCreatedSale(nil, flashSale, now)
+		Expect(err).ToNot(HaveOccurred())
+
+		emitted := c.Emitted()
+		Expect(emitted).To(HaveLen(1))
+		Expect(emitted[0].Topic).To(Equal(cfg.Kafka.ProducerEventTopic))
+		event := emitted[0].Event
+		Expect(event.EventAction).To(Equal("insert"))
+		Expect(event.ServiceAction).To(Equal("flashSaleCreated"))
+		Expect(event.UserUUID.String()).To(Equal(cfg.Authz.ServiceUser))
+		Expect(event.YearBucket).To(Equal(int16(now.Year())))
+
+		publishedSale := &FlashSale{}
+		err = json.Unmarshal(event.Data, publishedSale)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(publishedSale.FlashSaleID).To(Equal(flashSaleID))
+		Expect(publishedSale.Status).To(Equal(StatusDraft))
+	})
+	It("should send validation-Events as the service-user", func() {
+		cfg := config.Default()
+		cfg.Authz.ServiceUser = "2c6f1b8e-4a3d-4e5f-9b7a-1d0c8e6f4a21"
+		c := NewExecContext(cfg)
+
+		uuid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		cid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		event := c.validationEvent(uuid, cid, []byte("{}"))
+		Expect(event.ServiceAction).To(Equal("createFlashSale"))
+		Expect(event.UserUUID.String()).To(Equal(cfg.Authz.ServiceUser))
+		Expect(event.UUID).To(Equal(uuid))
+		Expect(event.CorrelationID).To(Equal(cid))
+	})
+})
@@ -0,0 +1,346 @@
+package flashsale
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// DiscountStep is the discount-percentage applied to lots which expire
+// within DaysToExpiry days.
+type DiscountStep struct {
+	DaysToExpiry int
+	Discount     float64
+}
+
+// ExpiryConfig configures generating FlashSales for inventory-lots approaching expiry.
+type ExpiryConfig struct {
+	// AutoCreate creates draft FlashSales when true, otherwise the
+	// FlashSales are only proposed as Documents. Each lot is proposed or
+	// created once for each step of DiscountCurve.
+	AutoCreate bool
+	// DaysBeforeExpiry is how many days before its expiry a lot becomes eligible.
+	DaysBeforeExpiry int
+	DiscountCurve    []DiscountStep
+	// InventoryCollection is the collection to read inventory-lots from.
+	InventoryCollection *mongo.Collection
+	Interval            time.Duration
+	// TimeZone is the IANA time zone in which the days to expiry are counted,
+	// and which is set on the generated FlashSales. Blank is UTC.
+	TimeZone string
+}
+
+// InventoryLot is the subset of an Inventory-Aggregate entry required for
+// generating FlashSales. This is the SchemaStruct for ExpiryConfig.InventoryCollection.
+type InventoryLot struct {
+	ItemID          string  `bson:"itemID,omitempty"`
+	UPC             string  `bson:"upc,omitempty"`
+	Lot             string  `bson:"lot,omitempty"`
+	SKU             string  `bson:"sku,omitempty"`
+	Price           float64 `bson:"price,omitempty"`
+	ExpiryDate      int64   `bson:"expiryDate,omitempty"`
+	TotalWeight     float64 `bson:"totalWeight,omitempty"`
+	SoldWeight      float64 `bson:"soldWeight,omitempty"`
+	WasteWeight     float64 `bson:"wasteWeight,omitempty"`
+	DonateWeight    float64 `bson:"donateWeight,omitempty"`
+	FlashSaleWeight float64 `bson:"flashSaleWeight,omitempty"`
+}
+
+func (l *InventoryLot) remainingWeight() float64 {
+	usedWeight := l.SoldWeight + l.WasteWeight + l.DonateWeight + l.FlashSaleWeight
+	return l.TotalWeight - usedWeight
+}
+
+// ExpiryScheduler periodically generates FlashSales for inventory-lots
+// which are within the configured number of days of expiry.
+type ExpiryScheduler struct {
+	execContext   *ExecContext
+	aggCollection *mongo.Collection
+	config        *ExpiryConfig
+	location      *time.Location
+	// proposed is the discount last proposed or created for each lot, so a lot
+	// is only proposed again once its discount changes. This also prevents
+	// creating a lot again before its created FlashSale is polled.
+	proposed map[string]float64
+}
+
+// ParseDiscountCurve parses a discount-curve of format "days:discount,days:discount",
+// such as "3:20,2:35,1:50", where discount is a percentage.
+func ParseDiscountCurve(curve string) ([]DiscountStep, error) {
+	steps := make([]DiscountStep, 0)
+	for _, stepStr := range strings.Split(curve, ",") {
+		stepStr = strings.TrimSpace(stepStr)
+		if stepStr == "" {
+			continue
+		}
+		parts := strings.Split(stepStr, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid discount-step: %s", stepStr)
+		}
+
+		days, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || days <= 0 {
+			return nil, errors.Errorf("invalid days in discount-step: %s", stepStr)
+		}
+		discount, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || discount <= 0 || discount >= 100 {
+			return nil, errors.Errorf("invalid discount in discount-step: %s", stepStr)
+		}
+		steps = append(steps, DiscountStep{
+			DaysToExpiry: days,
+			Discount:     discount,
+		})
+	}
+
+	if len(steps) == 0 {
+		return nil, errors.New("discount-curve has no steps")
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].DaysToExpiry < steps[j].DaysToExpiry
+	})
+	return steps, nil
+}
+
+// discountFor returns the discount for a lot expiring in provided days.
+// False is returned if no step in the curve covers the days.
+func discountFor(curve []DiscountStep, daysToExpiry int) (float64, bool) {
+	for _, step := range curve {
+		if daysToExpiry <= step.DaysToExpiry {
+			return step.Discount, true
+		}
+	}
+	return 0, false
+}
+
+// NewExpiryScheduler creates a new ExpiryScheduler. The generated FlashSales
+// are created in the provided ExecContext.
+func NewExpiryScheduler(
+	c *ExecContext,
+	aggCollection *mongo.Collection,
+	config *ExpiryConfig,
+) (*ExpiryScheduler, error) {
+	if c == nil {
+		return nil, errors.New("ExecContext cannot be nil")
+	}
+	if aggCollection == nil {
+		return nil, errors.New("aggregate-collection cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.InventoryCollection == nil {
+		return nil, errors.New("InventoryCollection cannot be nil")
+	}
+	if config.DaysBeforeExpiry <= 0 {
+		return nil, errors.New("DaysBeforeExpiry must be greater than 0")
+	}
+	if len(config.DiscountCurve) == 0 {
+		return nil, errors.New("DiscountCurve cannot be empty")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("Interval must be greater than 0")
+	}
+	loc, err := loadTimeZone(config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExpiryScheduler{
+		execContext:   c,
+		aggCollection: aggCollection,
+		config:        config,
+		location:      loc,
+		proposed:      map[string]float64{},
+	}, nil
+}
+
+// Run checks for expiring lots every Interval until the context is closed.
+// The resulting proposal and error Documents are sent on the docs channel.
+func (s *ExpiryScheduler) Run(ctx context.Context, docs chan<- *model.Document) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, doc := range s.Check(time.Now()) {
+			docs <- doc
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("ExpiryScheduler: context closed")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check generates FlashSales for the lots expiring within DaysBeforeExpiry of
+// provided time. Proposed FlashSales and any creation-errors are returned as Documents.
+func (s *ExpiryScheduler) Check(now time.Time) []*model.Document {
+	docs := make([]*model.Document, 0)
+
+	// Days are added on the local calendar, so DST-transitions are accounted for
+	lastExpiry := now.In(s.location).AddDate(0, 0, s.config.DaysBeforeExpiry)
+	findResults, err := s.config.InventoryCollection.Find(map[string]interface{}{
+		"expiryDate": map[string]interface{}{
+			"$gt":  now.Unix(),
+			"$lte": lastExpiry.Unix(),
+		},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "ExpiryScheduler: Error finding expiring lots")
+		log.Println(err)
+		return docs
+	}
+
+	expiringLots := map[string]bool{}
+	for _, r := range findResults {
+		lot, assertOK := r.(*InventoryLot)
+		if !assertOK {
+			log.Println("ExpiryScheduler: Error asserting find-result to inventory-lot")
+			continue
+		}
+		expiringLots[lotKey(lot)] = true
+		doc, err := s.generateSale(lot, now)
+		if err != nil {
+			err = errors.Wrapf(
+				err, "ExpiryScheduler: Error generating FlashSale for lot %s", lot.Lot,
+			)
+			log.Println(err)
+			continue
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	s.pruneProposals(expiringLots)
+	return docs
+}
+
+// lotKey identifies the lot in proposals.
+func lotKey(lot *InventoryLot) string {
+	return lot.ItemID + "/" + lot.Lot
+}
+
+// isProposed checks if the lot was already proposed with provided discount.
+func (s *ExpiryScheduler) isProposed(key string, discount float64) bool {
+	proposedDiscount, ok := s.proposed[key]
+	return ok && proposedDiscount == discount
+}
+
+// pruneProposals removes the proposals of lots which are no longer expiring,
+// such as the expired lots.
+func (s *ExpiryScheduler) pruneProposals(expiringLots map[string]bool) {
+	for key := range s.proposed {
+		if !expiringLots[key] {
+			delete(s.proposed, key)
+		}
+	}
+}
+
+// generateSale proposes or creates a draft FlashSale for the lot.
+// A nil Document is returned if the lot is ineligible or
+// if the FlashSale was published without errors.
+func (s *ExpiryScheduler) generateSale(
+	lot *InventoryLot,
+	now time.Time,
+) (*model.Document, error) {
+	weight := lot.remainingWeight()
+	if weight <= 0 {
+		return nil, nil
+	}
+
+	expiry := time.Unix(lot.ExpiryDate, 0)
+	daysToExpiry := daysUntil(now, expiry, s.location)
+	discount, isEligible := discountFor(s.config.DiscountCurve, daysToExpiry)
+	if !isEligible {
+		return nil, nil
+	}
+	if s.isProposed(lotKey(lot), discount) {
+		return nil, nil
+	}
+
+	itemID, err := uuuid.FromString(lot.ItemID)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing ItemID")
+		return nil, err
+	}
+	flashSaleID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating FlashSaleID")
+		return nil, err
+	}
+	flashSale := &FlashSale{
+		FlashSaleID: flashSaleID,
+		Items: []SoldItem{
+			SoldItem{
+				ItemID:   itemID,
+				UPC:      lot.UPC,
+				Weight:   weight,
+				Lot:      lot.Lot,
+				SKU:      lot.SKU,
+				Price:    lot.Price * (100 - discount) / 100,
+				Discount: discount,
+			},
+		},
+		StartTime: now.Unix(),
+		EndTime:   lot.ExpiryDate,
+		Status:    StatusDraft,
+		Timestamp: now.Unix(),
+		TimeZone:  s.config.TimeZone,
+	}
+
+	// Lots already on sale are skipped
+	conflictIDs, err := findConflictingSales(s.aggCollection, flashSale)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflictIDs) > 0 {
+		return nil, nil
+	}
+
+	if s.config.AutoCreate {
+		err = s.execContext.publishCreatedSale(s.aggCollection, flashSale, now)
+		if err != nil {
+			err = errors.Wrap(err, "Error publishing FlashSale")
+			return nil, err
+		}
+		s.proposed[lotKey(lot)] = discount
+		return nil, nil
+	}
+
+	marshalSale, err := json.Marshal(flashSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling FlashSale")
+		return nil, err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return nil, err
+	}
+	cid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+
+	s.proposed[lotKey(lot)] = discount
+	return &model.Document{
+		AggregateID:   AggregateID,
+		CorrelationID: cid,
+		EventAction:   "insert",
+		Result:        marshalSale,
+		ServiceAction: "flashSaleProposed",
+		UUID:          uuid,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package flashsale
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExpiryScheduler", func() {
+	Describe("ParseDiscountCurve", func() {
+		It("should parse and sort discount-steps by days", func() {
+			curve, err := ParseDiscountCurve("1:50, 3:20,2:35")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(curve).To(Equal([]DiscountStep{
+				DiscountStep{DaysToExpiry: 1, Discount: 50},
+				DiscountStep{DaysToExpiry: 2, Discount: 35},
+				DiscountStep{DaysToExpiry: 3, Discount: 20},
+			}))
+		})
+
+		It("should return error if curve is empty", func() {
+			_, err := ParseDiscountCurve("")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return error if discount is out of range", func() {
+			_, err := ParseDiscountCurve("3:20,1:100")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return error if step is malformed", func() {
+			_, err := ParseDiscountCurve("3-20")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("discountFor", func() {
+		var curve []DiscountStep
+
+		BeforeEach(func() {
+			var err error
+			curve, err = ParseDiscountCurve("3:20,2:35,1:50")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return the discount of closest step covering the days", func() {
+			discount, isEligible := discountFor(curve, 2)
+			Expect(isEligible).To(BeTrue())
+			Expect(discount).To(Equal(float64(35)))
+
+			discount, isEligible = discountFor(curve, 1)
+			Expect(isEligible).To(BeTrue())
+			Expect(discount).To(Equal(float64(50)))
+		})
+
+		It("should not be eligible if days are beyond the curve", func() {
+			_, isEligible := discountFor(curve, 4)
+			Expect(isEligible).To(BeFalse())
+		})
+	})
+
+	Describe("proposals", func() {
+		It("should propose lots again only once their discount changes", func() {
+			s := &ExpiryScheduler{
+				proposed: map[string]float64{},
+			}
+			key := lotKey(&InventoryLot{ItemID: "item-1", Lot: "lot-1"})
+			Expect(s.isProposed(key, 20)).To(BeFalse())
+
+			s.proposed[key] = 20
+			Expect(s.isProposed(key, 20)).To(BeTrue())
+			Expect(s.isProposed(key, 35)).To(BeFalse())
+		})
+
+		It("should prune proposals of lots no longer expiring", func() {
+			s := &ExpiryScheduler{
+				proposed: map[string]float64{
+					"item-1/lot-1": 20,
+					"item-1/lot-2": 35,
+				},
+			}
+			s.pruneProposals(map[string]bool{"item-1/lot-2": true})
+			Expect(s.proposed).To(Equal(map[string]float64{"item-1/lot-2": 35}))
+		})
+	})
+})
@@ -0,0 +1,119 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// SaleFilter selects FlashSales. Blank fields are not used for filtering.
+type SaleFilter struct {
+	// From and To select FlashSales whose time-window overlaps [From, To).
+	From   int64  `json:"from,omitempty"`
+	To     int64  `json:"to,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// Item-fields select FlashSales having an item matching all provided fields.
+	ItemID string `json:"itemID,omitempty"`
+	SKU    string `json:"sku,omitempty"`
+	UPC    string `json:"upc,omitempty"`
+	Lot    string `json:"lot,omitempty"`
+
+	// Region and StoreID select FlashSales applying to the Region or store,
+	// including the FlashSales without Region or StoreIDs.
+	Region  string `json:"region,omitempty"`
+	StoreID string `json:"storeID,omitempty"`
+}
+
+// Query returns the Mongo-filter for the SaleFilter.
+func (f *SaleFilter) Query() map[string]interface{} {
+	query := map[string]interface{}{}
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+
+	itemQuery := map[string]interface{}{}
+	if f.ItemID != "" {
+		itemQuery["itemID"] = f.ItemID
+	}
+	if f.SKU != "" {
+		itemQuery["sku"] = f.SKU
+	}
+	if f.UPC != "" {
+		itemQuery["upc"] = f.UPC
+	}
+	if f.Lot != "" {
+		itemQuery["lot"] = f.Lot
+	}
+	if len(itemQuery) > 0 {
+		query["items"] = map[string]interface{}{
+			"$elemMatch": itemQuery,
+		}
+	}
+
+	storeIDs := []string{}
+	if f.StoreID != "" {
+		storeIDs = append(storeIDs, f.StoreID)
+	}
+	andFilters := append(
+		storeScopeFilters(f.Region, storeIDs),
+		overlapWindowFilters(f.From, f.To)...,
+	)
+	if len(andFilters) > 0 {
+		query["$and"] = andFilters
+	}
+	return query
+}
+
+// StreamSales finds the FlashSales matching the filter in batches of provided size,
+// and calls the callback for each FlashSale in order of their ObjectIDs.
+// Streaming stops at the first error returned by the callback.
+func StreamSales(
+	collection *mongo.Collection,
+	filter *SaleFilter,
+	batchSize int64,
+	callback func(*FlashSale) error,
+) error {
+	if batchSize <= 0 {
+		return errors.New("batchSize must be greater than 0")
+	}
+
+	lastID := objectid.NilObjectID
+	for {
+		query := filter.Query()
+		if lastID != objectid.NilObjectID {
+			query["_id"] = map[string]interface{}{
+				"$gt": lastID,
+			}
+		}
+
+		findResults, err := collection.Find(
+			query,
+			findopt.Sort(map[string]interface{}{
+				"_id": 1,
+			}),
+			findopt.Limit(batchSize),
+		)
+		if err != nil {
+			err = errors.Wrap(err, "StreamSales: Error finding FlashSales")
+			return err
+		}
+
+		for _, r := range findResults {
+			flashSale, assertOK := r.(*FlashSale)
+			if !assertOK {
+				return errors.New("StreamSales: Error asserting find-result to FlashSale")
+			}
+			err = callback(flashSale)
+			if err != nil {
+				return err
+			}
+			lastID = flashSale.ID
+		}
+
+		if int64(len(findResults)) < batchSize {
+			return nil
+		}
+	}
+}
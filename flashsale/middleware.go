@@ -0,0 +1,219 @@
+package flashsale
+
+import (
+	"expvar"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// HandlerPanics counts the panics recovered from Handlers. It is published
+// with expvar as "flashsale_handler_panics".
+var HandlerPanics = expvar.NewInt("flashsale_handler_panics")
+
+// Middleware wraps a Handler, such as for running common code before and after
+// each Event is handled.
+type Middleware func(next Handler) Handler
+
+// Chain composes the Middlewares into one. The first Middleware is the
+// outermost, and so runs first when an Event is handled.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// Use adds the Middlewares around the Handlers run by ExecContext.Handle.
+// Middlewares added earlier are outermost.
+func (c *ExecContext) Use(middlewares ...Middleware) {
+	c.middlewaresLock.Lock()
+	defer c.middlewaresLock.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// handler returns the Registry-dispatch wrapped in Middlewares.
+func (c *ExecContext) handler() Handler {
+	c.middlewaresLock.RLock()
+	defer c.middlewaresLock.RUnlock()
+	return Chain(c.middlewares...)(c.registry.dispatch)
+}
+
+// Recover recovers the panics in Handlers, so a panic while handling an Event
+// does not stop the service. The stack-trace is logged, the panic is counted in
+// HandlerPanics, and an InternalError Document is returned for the Event.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) (doc *model.Document) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				HandlerPanics.Add(1)
+				err := errors.Errorf("panic: %v", r)
+				err = errors.Wrapf(err, "Error handling Event: %s", event.UUID)
+				log.Printf("%s\n%s", err, debug.Stack())
+				doc = &model.Document{
+					AggregateID:   event.AggregateID,
+					CorrelationID: event.CorrelationID,
+					Error:         err.Error(),
+					ErrorCode:     InternalError,
+					EventAction:   event.EventAction,
+					ServiceAction: event.ServiceAction,
+					UUID:          event.UUID,
+				}
+			}()
+			return next(c, collection, event)
+		}
+	}
+}
+
+// Logging logs the Events as they are handled, and the errors in their
+// response-Documents.
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			log.Printf(
+				"Handling Event: %s, EventAction: %s, ServiceAction: %s, "+
+					"CorrelationID: %s",
+				event.UUID, event.EventAction, event.ServiceAction, event.CorrelationID,
+			)
+			doc := next(c, collection, event)
+			if doc != nil && doc.Error != "" {
+				log.Printf(
+					"Event: %s returned ErrorCode: %d, Error: %s",
+					event.UUID, doc.ErrorCode, doc.Error,
+				)
+			}
+			return doc
+		}
+	}
+}
+
+// Timing logs the time taken for handling each Event.
+func Timing() Middleware {
+	return func(next Handler) Handler {
+		return func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			start := time.Now()
+			doc := next(c, collection, event)
+			log.Printf(
+				"Handled Event: %s, EventAction: %s, ServiceAction: %s in %s",
+				event.UUID, event.EventAction, event.ServiceAction, time.Since(start),
+			)
+			return doc
+		}
+	}
+}
+
+// Dedup skips the Events whose UUID matches one of the last "size" Events
+// handled without error, such as Events redelivered by Kafka. No response-Document
+// is returned for the skipped Events, since it was returned when the Event was
+// first handled. Events whose Handler returns an error-Document or panics are
+// forgotten, so they are handled again if redelivered.
+// The Events without a UUID are always handled.
+func Dedup(size int) Middleware {
+	seen := newUUIDSet(size)
+	return func(next Handler) Handler {
+		return func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			if event.UUID == (uuuid.UUID{}) {
+				return next(c, collection, event)
+			}
+			// The UUID is added before handling, so a concurrent duplicate is skipped
+			if !seen.add(event.UUID) {
+				log.Printf(
+					"Skipping duplicate Event: %s, EventAction: %s, ServiceAction: %s",
+					event.UUID, event.EventAction, event.ServiceAction,
+				)
+				return nil
+			}
+
+			handled := false
+			defer func() {
+				if !handled {
+					seen.remove(event.UUID)
+				}
+			}()
+			doc := next(c, collection, event)
+			handled = doc == nil || doc.Error == ""
+			return doc
+		}
+	}
+}
+
+// uuidSet is a fixed-size set of UUIDs, which evicts the oldest UUID when full.
+type uuidSet struct {
+	lock  sync.Mutex
+	uuids map[uuuid.UUID]bool
+	ring  []uuuid.UUID
+	next  int
+}
+
+func newUUIDSet(size int) *uuidSet {
+	return &uuidSet{
+		uuids: map[uuuid.UUID]bool{},
+		ring:  make([]uuuid.UUID, 0, size),
+	}
+}
+
+// add adds the UUID, and returns false if the UUID was already in the set.
+func (s *uuidSet) add(uuid uuuid.UUID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.uuids[uuid] {
+		return false
+	}
+	if cap(s.ring) == 0 {
+		return true
+	}
+
+	if len(s.ring) < cap(s.ring) {
+		s.ring = append(s.ring, uuid)
+	} else {
+		delete(s.uuids, s.ring[s.next])
+		s.ring[s.next] = uuid
+		s.next = (s.next + 1) % len(s.ring)
+	}
+	s.uuids[uuid] = true
+	return true
+}
+
+// remove removes the UUID from the set.
+func (s *uuidSet) remove(uuid uuuid.UUID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.uuids[uuid] {
+		return
+	}
+	delete(s.uuids, uuid)
+	// The slot is cleared so its eviction doesn't remove the UUID if added again
+	for i := range s.ring {
+		if s.ring[i] == uuid {
+			s.ring[i] = uuuid.UUID{}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		calls   []string
+		handler Handler
+	)
+
+	// record returns a Middleware which records its name when run.
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(
+				c *ExecContext,
+				collection *mongo.Collection,
+				event *model.Event,
+			) *model.Document {
+				calls = append(calls, name)
+				return next(c, collection, event)
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		calls = []string{}
+		handler = func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			calls = append(calls, "handler")
+			return &model.Document{
+				UUID: event.UUID,
+			}
+		}
+	})
+
+	It("should run chained Middlewares in order", func() {
+		h := Chain(record("first"), record("second"))(handler)
+		h(nil, nil, &model.Event{})
+		Expect(calls).To(Equal([]string{"first", "second", "handler"}))
+	})
+
+	It("should run Middlewares added to ExecContext around Handlers", func() {
+		c := NewExecContext(config.Default())
+		c.registry = NewRegistry()
+		err := c.registry.Register("insert", "", handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		c.Use(record("first"))
+		c.Use(record("second"))
+		doc := c.Handle(nil, &model.Event{
+			EventAction: "insert",
+		})
+		Expect(doc).ToNot(BeNil())
+		Expect(calls).To(Equal([]string{"first", "second", "handler"}))
+	})
+
+	Describe("Recover", func() {
+		It("should return InternalError Document for panics in Handlers", func() {
+			panics := HandlerPanics.Value()
+			h := Recover()(func(
+				c *ExecContext,
+				collection *mongo.Collection,
+				event *model.Event,
+			) *model.Document {
+				var sale *FlashSale
+				sale.FlashSaleID = event.UUID
+				return nil
+			})
+
+			cid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			event := &model.Event{
+				AggregateID:   AggregateID,
+				CorrelationID: cid,
+				EventAction:   "update",
+			}
+			var doc *model.Document
+			Expect(func() {
+				doc = h(nil, nil, event)
+			}).ToNot(Panic())
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+			Expect(doc.CorrelationID).To(Equal(cid))
+			Expect(doc.EventAction).To(Equal("update"))
+			Expect(HandlerPanics.Value()).To(Equal(panics + 1))
+		})
+
+		It("should return Handler's Document if there is no panic", func() {
+			doc := Recover()(handler)(nil, nil, &model.Event{})
+			Expect(doc).ToNot(BeNil())
+			Expect(doc.Error).To(BeEmpty())
+		})
+	})
+
+	Describe("Dedup", func() {
+		It("should skip Events already handled", func() {
+			h := Dedup(2)(handler)
+			uuid1, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			uuid2, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(h(nil, nil, &model.Event{UUID: uuid1})).ToNot(BeNil())
+			Expect(h(nil, nil, &model.Event{UUID: uuid2})).ToNot(BeNil())
+			Expect(h(nil, nil, &model.Event{UUID: uuid1})).To(BeNil())
+			Expect(calls).To(HaveLen(2))
+		})
+
+		It("should forget the oldest Events when full", func() {
+			h := Dedup(1)(handler)
+			uuid1, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			uuid2, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			h(nil, nil, &model.Event{UUID: uuid1})
+			h(nil, nil, &model.Event{UUID: uuid2})
+			Expect(h(nil, nil, &model.Event{UUID: uuid1})).ToNot(BeNil())
+			Expect(calls).To(HaveLen(3))
+		})
+
+		It("should handle Events again if they returned error", func() {
+			failing := func(
+				c *ExecContext,
+				collection *mongo.Collection,
+				event *model.Event,
+			) *model.Document {
+				calls = append(calls, "failing")
+				return &model.Document{
+					Error:     "some-error",
+					ErrorCode: DatabaseError,
+				}
+			}
+			h := Dedup(2)(failing)
+			uuid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			h(nil, nil, &model.Event{UUID: uuid})
+			Expect(h(nil, nil, &model.Event{UUID: uuid})).ToNot(BeNil())
+			Expect(calls).To(HaveLen(2))
+		})
+
+		It("should handle Events again if they panicked", func() {
+			panicking := func(
+				c *ExecContext,
+				collection *mongo.Collection,
+				event *model.Event,
+			) *model.Document {
+				panic("some-panic")
+			}
+			uuid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			dedup := Dedup(2)
+			doc := Recover()(dedup(panicking))(nil, nil, &model.Event{UUID: uuid})
+			Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+
+			Expect(dedup(handler)(nil, nil, &model.Event{UUID: uuid})).ToNot(BeNil())
+			Expect(calls).To(HaveLen(1))
+		})
+
+		It("should handle all Events if size is 0", func() {
+			h := Dedup(0)(handler)
+			uuid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			h(nil, nil, &model.Event{UUID: uuid})
+			h(nil, nil, &model.Event{UUID: uuid})
+			Expect(calls).To(HaveLen(2))
+		})
+	})
+})
@@ -1,7 +1,9 @@
 package flashsale
 
 import (
+	"crypto/sha1"
 	"encoding/json"
+	"time"
 
 	"github.com/TerrexTech/uuuid"
 	"github.com/mongodb/mongo-go-driver/bson"
@@ -12,60 +14,112 @@ import (
 // AggregateID is the global AggregateID for FlashSale Aggregate.
 const AggregateID int8 = 7
 
+// StatusActive is the Status of a FlashSale which is live during its time-window.
+const StatusActive = "active"
+
+// StatusDraft is the Status of a FlashSale which was generated, but is yet to be
+// reviewed and activated.
+const StatusDraft = "draft"
+
+// StatusPendingApproval is the Status of a new FlashSale whose discounts exceed
+// the approval-thresholds, and which is yet to be approved or rejected.
+const StatusPendingApproval = "pendingApproval"
+
+// StatusApproved is the Status of an approved FlashSale, which is activated once
+// its items are validated by the inventory.
+const StatusApproved = "approved"
+
+// StatusRejected is the Status of a FlashSale which was rejected in approval.
+const StatusRejected = "rejected"
+
 // FlashSale defines the FlashSale Aggregate.
 type FlashSale struct {
-	ID        objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	FlashSaleID    uuuid.UUID        `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
-	Items     []SoldItem        `bson:"items,omitempty" json:"items,omitempty"`
-	Timestamp int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	ID          objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	FlashSaleID uuuid.UUID        `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
+	Items       []SoldItem        `bson:"items,omitempty" json:"items,omitempty"`
+	StartTime   int64             `bson:"startTime,omitempty" json:"startTime,omitempty"`
+	EndTime     int64             `bson:"endTime,omitempty" json:"endTime,omitempty"`
+	Status      string            `bson:"status,omitempty" json:"status,omitempty"`
+	Timestamp   int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	// ReviewedBy is the UserUUID of the user who approved or rejected the FlashSale.
+	ReviewedBy uuuid.UUID `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt int64      `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	// SubmittedBy is the UserUUID of the user who submitted the FlashSale for
+	// approval. The user cannot approve or reject the FlashSale.
+	SubmittedBy uuuid.UUID `bson:"submittedBy,omitempty" json:"submittedBy,omitempty"`
+	// Region and StoreIDs are the stores the FlashSale applies to. A FlashSale
+	// without StoreIDs applies to all stores in its Region, or all stores if
+	// Region is also blank.
+	Region   string   `bson:"region,omitempty" json:"region,omitempty"`
+	StoreIDs []string `bson:"storeIDs,omitempty" json:"storeIDs,omitempty"`
+	// TimeZone is the IANA time zone, such as "America/Toronto", in which the
+	// StartTime and EndTime are rendered as local times. Blank is UTC.
+	TimeZone string `bson:"timeZone,omitempty" json:"timeZone,omitempty"`
 }
 
 // SoldItem defines an item in a flashSale.
 type SoldItem struct {
-	ItemID uuuid.UUID `bson:"itemID,omitempty" json:"itemID,omitempty"`
-	UPC    string     `bson:"upc,omitempty" json:"upc,omitempty"`
-	Weight float64    `bson:"weight,omitempty" json:"weight,omitempty"`
-	Lot    string     `bson:"lot,omitempty" json:"lot,omitempty"`
-	SKU    string     `bson:"sku,omitempty" json:"sku,omitempty"`
+	ItemID   uuuid.UUID `bson:"itemID,omitempty" json:"itemID,omitempty"`
+	UPC      string     `bson:"upc,omitempty" json:"upc,omitempty"`
+	Weight   float64    `bson:"weight,omitempty" json:"weight,omitempty"`
+	Lot      string     `bson:"lot,omitempty" json:"lot,omitempty"`
+	SKU      string     `bson:"sku,omitempty" json:"sku,omitempty"`
+	Price    float64    `bson:"price,omitempty" json:"price,omitempty"`
+	Discount float64    `bson:"discount,omitempty" json:"discount,omitempty"`
 }
 
 // BSON#Unmarshal errors out when unmarshalling to map due to presence of array.
 // Since we can't directly unmarshal to FlashSale, hence this. There has to be a better way.
 type flashSaleBSON struct {
-	ID        objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	FlashSaleID    string            `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
-	Items     []soldItemXSON    `bson:"items,omitempty" json:"items,omitempty"`
-	Timestamp int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	ID          objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	FlashSaleID string            `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
+	Items       []soldItemXSON    `bson:"items,omitempty" json:"items,omitempty"`
+	StartTime   int64             `bson:"startTime,omitempty" json:"startTime,omitempty"`
+	EndTime     int64             `bson:"endTime,omitempty" json:"endTime,omitempty"`
+	Status      string            `bson:"status,omitempty" json:"status,omitempty"`
+	Timestamp   int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	ReviewedBy  string            `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt  int64             `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	SubmittedBy string            `bson:"submittedBy,omitempty" json:"submittedBy,omitempty"`
+	Region      string            `bson:"region,omitempty" json:"region,omitempty"`
+	StoreIDs    []string          `bson:"storeIDs,omitempty" json:"storeIDs,omitempty"`
+	TimeZone    string            `bson:"timeZone,omitempty" json:"timeZone,omitempty"`
 }
 
 // Same as flashSaleBSON
 type flashSaleJSON struct {
-	ID        string         `bson:"_id,omitempty" json:"_id,omitempty"`
-	FlashSaleID    string         `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
-	Items     []soldItemXSON `bson:"items,omitempty" json:"items,omitempty"`
-	Timestamp int64          `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	ID          string         `bson:"_id,omitempty" json:"_id,omitempty"`
+	FlashSaleID string         `bson:"flashSaleID,omitempty" json:"flashSaleID,omitempty"`
+	Items       []soldItemXSON `bson:"items,omitempty" json:"items,omitempty"`
+	StartTime   int64          `bson:"startTime,omitempty" json:"startTime,omitempty"`
+	EndTime     int64          `bson:"endTime,omitempty" json:"endTime,omitempty"`
+	Status      string         `bson:"status,omitempty" json:"status,omitempty"`
+	Timestamp   int64          `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	ReviewedBy  string         `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt  int64          `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	SubmittedBy string         `bson:"submittedBy,omitempty" json:"submittedBy,omitempty"`
+	Region      string         `bson:"region,omitempty" json:"region,omitempty"`
+	StoreIDs    []string       `bson:"storeIDs,omitempty" json:"storeIDs,omitempty"`
+	TimeZone    string         `bson:"timeZone,omitempty" json:"timeZone,omitempty"`
+	// StartTimeLocal and EndTimeLocal are the RFC3339 or local times (without
+	// UTC-offset) in TimeZone. These set StartTime and EndTime if those are not set.
+	StartTimeLocal string `json:"startTimeLocal,omitempty"`
+	EndTimeLocal   string `json:"endTimeLocal,omitempty"`
 }
 
 type soldItemXSON struct {
-	ItemID string  `bson:"itemID,omitempty" json:"itemID,omitempty"`
-	UPC    string  `bson:"upc,omitempty" json:"upc,omitempty"`
-	Weight float64 `bson:"weight,omitempty" json:"weight,omitempty"`
-	Lot    string  `bson:"lot,omitempty" json:"lot,omitempty"`
-	SKU    string  `bson:"sku,omitempty" json:"sku,omitempty"`
+	ItemID   string  `bson:"itemID,omitempty" json:"itemID,omitempty"`
+	UPC      string  `bson:"upc,omitempty" json:"upc,omitempty"`
+	Weight   float64 `bson:"weight,omitempty" json:"weight,omitempty"`
+	Lot      string  `bson:"lot,omitempty" json:"lot,omitempty"`
+	SKU      string  `bson:"sku,omitempty" json:"sku,omitempty"`
+	Price    float64 `bson:"price,omitempty" json:"price,omitempty"`
+	Discount float64 `bson:"discount,omitempty" json:"discount,omitempty"`
 }
 
 // MarshalBSON returns bytes of BSON-type.
 func (s FlashSale) MarshalBSON() ([]byte, error) {
-	items := make([]map[string]interface{}, 0)
-	for _, item := range s.Items {
-		items = append(items, map[string]interface{}{
-			"itemID": item.ItemID.String(),
-			"upc":    item.UPC,
-			"weight": item.Weight,
-			"lot":    item.Lot,
-			"sku":    item.SKU,
-		})
-	}
+	items := soldItemsToMaps(s.Items)
 
 	in := map[string]interface{}{
 		"timestamp": s.Timestamp,
@@ -73,6 +127,33 @@ func (s FlashSale) MarshalBSON() ([]byte, error) {
 	if s.FlashSaleID != (uuuid.UUID{}) {
 		in["flashSaleID"] = s.FlashSaleID.String()
 	}
+	if s.StartTime != 0 {
+		in["startTime"] = s.StartTime
+	}
+	if s.EndTime != 0 {
+		in["endTime"] = s.EndTime
+	}
+	if s.Status != "" {
+		in["status"] = s.Status
+	}
+	if s.ReviewedBy != (uuuid.UUID{}) {
+		in["reviewedBy"] = s.ReviewedBy.String()
+	}
+	if s.ReviewedAt != 0 {
+		in["reviewedAt"] = s.ReviewedAt
+	}
+	if s.SubmittedBy != (uuuid.UUID{}) {
+		in["submittedBy"] = s.SubmittedBy.String()
+	}
+	if s.Region != "" {
+		in["region"] = s.Region
+	}
+	if len(s.StoreIDs) > 0 {
+		in["storeIDs"] = s.StoreIDs
+	}
+	if s.TimeZone != "" {
+		in["timeZone"] = s.TimeZone
+	}
 
 	if s.ID != objectid.NilObjectID {
 		in["_id"] = s.ID
@@ -86,16 +167,7 @@ func (s FlashSale) MarshalBSON() ([]byte, error) {
 
 // MarshalJSON returns bytes of JSON-type.
 func (s *FlashSale) MarshalJSON() ([]byte, error) {
-	items := make([]map[string]interface{}, 0)
-	for _, item := range s.Items {
-		items = append(items, map[string]interface{}{
-			"itemID": item.ItemID.String(),
-			"upc":    item.UPC,
-			"weight": item.Weight,
-			"lot":    item.Lot,
-			"sku":    item.SKU,
-		})
-	}
+	items := soldItemsToMaps(s.Items)
 
 	in := map[string]interface{}{
 		"timestamp": s.Timestamp,
@@ -107,10 +179,42 @@ func (s *FlashSale) MarshalJSON() ([]byte, error) {
 	if s.FlashSaleID != (uuuid.UUID{}) {
 		in["flashSaleID"] = s.FlashSaleID.String()
 	}
+	if s.StartTime != 0 {
+		in["startTime"] = s.StartTime
+	}
+	if s.EndTime != 0 {
+		in["endTime"] = s.EndTime
+	}
+	if s.Status != "" {
+		in["status"] = s.Status
+	}
+	if s.ReviewedBy != (uuuid.UUID{}) {
+		in["reviewedBy"] = s.ReviewedBy.String()
+	}
+	if s.ReviewedAt != 0 {
+		in["reviewedAt"] = s.ReviewedAt
+	}
+	if s.SubmittedBy != (uuuid.UUID{}) {
+		in["submittedBy"] = s.SubmittedBy.String()
+	}
+	if s.Region != "" {
+		in["region"] = s.Region
+	}
+	if len(s.StoreIDs) > 0 {
+		in["storeIDs"] = s.StoreIDs
+	}
+	if s.TimeZone != "" {
+		in["timeZone"] = s.TimeZone
+	}
 	if len(items) > 0 {
 		in["items"] = items
 	}
 
+	err := s.marshalLocalTimes(in)
+	if err != nil {
+		err = errors.Wrap(err, "MarshalJSON Error")
+		return nil, err
+	}
 	return json.Marshal(in)
 }
 
@@ -123,7 +227,14 @@ func (s *FlashSale) UnmarshalBSON(in []byte) error {
 		return err
 	}
 
+	s.StartTime = sb.StartTime
+	s.EndTime = sb.EndTime
+	s.Status = sb.Status
 	s.Timestamp = sb.Timestamp
+	s.ReviewedAt = sb.ReviewedAt
+	s.Region = sb.Region
+	s.StoreIDs = sb.StoreIDs
+	s.TimeZone = sb.TimeZone
 
 	if sb.ID != objectid.NilObjectID {
 		s.ID = sb.ID
@@ -134,23 +245,30 @@ func (s *FlashSale) UnmarshalBSON(in []byte) error {
 	}
 	s.FlashSaleID = flashSaleID
 
-	if s.Items == nil {
-		s.Items = make([]SoldItem, 0)
+	if sb.ReviewedBy != "" {
+		s.ReviewedBy, err = uuuid.FromString(sb.ReviewedBy)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing ReviewedBy")
+			return err
+		}
 	}
-	for _, item := range sb.Items {
-		itemID, err := uuuid.FromString(item.ItemID)
+	if sb.SubmittedBy != "" {
+		s.SubmittedBy, err = uuuid.FromString(sb.SubmittedBy)
 		if err != nil {
-			err = errors.Wrap(err, "UnmarshalBSON: Error parsing ItemID")
+			err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing SubmittedBy")
 			return err
 		}
-		s.Items = append(s.Items, SoldItem{
-			ItemID: itemID,
-			UPC:    item.UPC,
-			Weight: item.Weight,
-			Lot:    item.Lot,
-			SKU:    item.SKU,
-		})
 	}
+
+	items, err := soldItemsFromXSON(sb.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON")
+		return err
+	}
+	if s.Items == nil {
+		s.Items = make([]SoldItem, 0)
+	}
+	s.Items = append(s.Items, items...)
 	return nil
 }
 
@@ -163,7 +281,14 @@ func (s *FlashSale) UnmarshalJSON(in []byte) error {
 		return err
 	}
 
+	s.StartTime = sb.StartTime
+	s.EndTime = sb.EndTime
+	s.Status = sb.Status
 	s.Timestamp = sb.Timestamp
+	s.ReviewedAt = sb.ReviewedAt
+	s.Region = sb.Region
+	s.StoreIDs = sb.StoreIDs
+	s.TimeZone = sb.TimeZone
 
 	if sb.ID != "" && sb.ID != objectid.NilObjectID.String() {
 		s.ID, err = objectid.FromHex(sb.ID)
@@ -177,23 +302,159 @@ func (s *FlashSale) UnmarshalJSON(in []byte) error {
 		err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing FlashSaleID")
 		return err
 	}
+	if sb.ReviewedBy != "" {
+		s.ReviewedBy, err = uuuid.FromString(sb.ReviewedBy)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing ReviewedBy")
+			return err
+		}
+	}
+	if sb.SubmittedBy != "" {
+		s.SubmittedBy, err = uuuid.FromString(sb.SubmittedBy)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing SubmittedBy")
+			return err
+		}
+	}
 
+	items, err := soldItemsFromXSON(sb.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON")
+		return err
+	}
 	if s.Items == nil {
 		s.Items = make([]SoldItem, 0)
 	}
-	for _, item := range sb.Items {
-		itemID, err := uuuid.FromString(item.ItemID)
+	s.Items = append(s.Items, items...)
+
+	err = s.unmarshalLocalTimes(sb.StartTimeLocal, sb.EndTimeLocal)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON Error")
+		return err
+	}
+	return nil
+}
+
+// marshalLocalTimes adds the StartTime and EndTime as RFC3339-times in UTC,
+// and in TimeZone if it is set.
+func (s *FlashSale) marshalLocalTimes(in map[string]interface{}) error {
+	if s.StartTime != 0 {
+		in["startTimeUTC"] = formatLocalTime(s.StartTime, time.UTC)
+	}
+	if s.EndTime != 0 {
+		in["endTimeUTC"] = formatLocalTime(s.EndTime, time.UTC)
+	}
+	if s.TimeZone == "" {
+		return nil
+	}
+
+	loc, err := loadTimeZone(s.TimeZone)
+	if err != nil {
+		return err
+	}
+	if s.StartTime != 0 {
+		in["startTimeLocal"] = formatLocalTime(s.StartTime, loc)
+	}
+	if s.EndTime != 0 {
+		in["endTimeLocal"] = formatLocalTime(s.EndTime, loc)
+	}
+	return nil
+}
+
+// unmarshalLocalTimes sets the StartTime and EndTime from the local times in
+// TimeZone. An error is returned if the local time differs from the already
+// set StartTime or EndTime.
+func (s *FlashSale) unmarshalLocalTimes(startLocal string, endLocal string) error {
+	if startLocal == "" && endLocal == "" {
+		return nil
+	}
+	loc, err := loadTimeZone(s.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	localTimes := []struct {
+		name  string
+		value string
+		dest  *int64
+	}{
+		{"StartTime", startLocal, &s.StartTime},
+		{"EndTime", endLocal, &s.EndTime},
+	}
+	for _, lt := range localTimes {
+		if lt.value == "" {
+			continue
+		}
+		unix, err := parseLocalTime(lt.value, loc)
 		if err != nil {
-			err = errors.Wrap(err, "UnmarshalJSON: Error parsing ItemID")
+			err = errors.Wrapf(err, "Error parsing local %s", lt.name)
 			return err
 		}
-		s.Items = append(s.Items, SoldItem{
-			ItemID: itemID,
-			UPC:    item.UPC,
-			Weight: item.Weight,
-			Lot:    item.Lot,
-			SKU:    item.SKU,
-		})
+		if *lt.dest != 0 && *lt.dest != unix {
+			return errors.Errorf("local %s does not match %s", lt.name, lt.name)
+		}
+		*lt.dest = unix
 	}
 	return nil
 }
+
+// soldItemsToMaps converts SoldItems to maps with string-ItemIDs for marshalling.
+func soldItemsToMaps(soldItems []SoldItem) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0)
+	for _, item := range soldItems {
+		items = append(items, map[string]interface{}{
+			"itemID":   item.ItemID.String(),
+			"upc":      item.UPC,
+			"weight":   item.Weight,
+			"lot":      item.Lot,
+			"sku":      item.SKU,
+			"price":    item.Price,
+			"discount": item.Discount,
+		})
+	}
+	return items
+}
+
+// soldItemsFromXSON converts unmarshalled items to SoldItems.
+func soldItemsFromXSON(xsonItems []soldItemXSON) ([]SoldItem, error) {
+	items := make([]SoldItem, 0)
+	for _, item := range xsonItems {
+		itemID, err := uuuid.FromString(item.ItemID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing ItemID")
+			return nil, err
+		}
+		items = append(items, SoldItem{
+			ItemID:   itemID,
+			UPC:      item.UPC,
+			Weight:   item.Weight,
+			Lot:      item.Lot,
+			SKU:      item.SKU,
+			Price:    item.Price,
+			Discount: item.Discount,
+		})
+	}
+	return items, nil
+}
+
+// derivedUUID returns the version-5 UUID for name in namespace, so the same UUID
+// is derived each time, such as when the Events are replayed.
+func derivedUUID(namespace uuuid.UUID, name string) uuuid.UUID {
+	hash := sha1.New()
+	hash.Write(namespace.UUID[:])
+	hash.Write([]byte(name))
+	sum := hash.Sum(nil)
+
+	id := uuuid.UUID{}
+	copy(id.UUID[:], sum)
+	id.UUID[6] = (id.UUID[6] & 0x0f) | 0x50
+	id.UUID[8] = (id.UUID[8] & 0x3f) | 0x80
+	return id
+}
+
+// eventSaleID returns the FlashSaleID for the FlashSale created by an Event.
+// The FlashSaleID is derived from the Event's UUID, so the FlashSale has the
+// same FlashSaleID when the Event is replayed.
+func eventSaleID(eventUUID uuuid.UUID) uuuid.UUID {
+	return derivedUUID(eventUUID, "flashSaleID")
+}
@@ -0,0 +1,221 @@
+package flashsale
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// OutboxStatusPending is the Status of OutboxEntries yet to be published.
+const OutboxStatusPending = "pending"
+
+// OutboxStatusSent is the Status of OutboxEntries published to Kafka.
+const OutboxStatusSent = "sent"
+
+// OutboxEntry is a Kafka-message to be published. The entries are written
+// along with the state-changes causing them, and are published by OutboxRelay.
+type OutboxEntry struct {
+	ID        objectid.ObjectID `bson:"_id,omitempty"`
+	Topic     string            `bson:"topic,omitempty"`
+	Payload   string            `bson:"payload,omitempty"`
+	Status    string            `bson:"status,omitempty"`
+	Attempts  int64             `bson:"attempts,omitempty"`
+	LastError string            `bson:"lastError,omitempty"`
+	CreatedAt int64             `bson:"createdAt,omitempty"`
+	SentAt    int64             `bson:"sentAt,omitempty"`
+}
+
+// MessageSender sends a message to Kafka, and returns once it is acknowledged.
+// This is implemented by sarama.SyncProducer.
+type MessageSender interface {
+	SendMessage(msg *sarama.ProducerMessage) (int32, int64, error)
+}
+
+// outboxCollection returns the collection with provided name storing OutboxEntries.
+func outboxCollection(
+	aggCollection *mongo.Collection,
+	name string,
+) (*mongo.Collection, error) {
+	indexConfigs := []mongo.IndexConfig{
+		mongo.IndexConfig{
+			ColumnConfig: []mongo.IndexColumnConfig{
+				mongo.IndexColumnConfig{
+					Name: "status",
+				},
+				mongo.IndexColumnConfig{
+					Name: "createdAt",
+				},
+			},
+			Name: "status_createdAt_index",
+		},
+	}
+	return siblingCollection(aggCollection, name, &OutboxEntry{}, indexConfigs)
+}
+
+// writeOutbox inserts a pending OutboxEntry for the payload.
+func (c *ExecContext) writeOutbox(
+	collection *mongo.Collection,
+	tx *txOptions,
+	topic string,
+	payload []byte,
+) error {
+	outboxColl, err := outboxCollection(collection, c.cfg.Mongo.OutboxCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Error getting outbox-collection")
+		return err
+	}
+	_, err = tx.insertOne(outboxColl, &OutboxEntry{
+		Topic:     topic,
+		Payload:   string(payload),
+		Status:    OutboxStatusPending,
+		CreatedAt: time.Now().UnixNano(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error inserting OutboxEntry")
+		return err
+	}
+	return nil
+}
+
+// OutboxRelay publishes the pending OutboxEntries to Kafka, in order of their
+// creation. An entry is marked as sent only after Kafka acknowledges it, so the
+// entries are delivered at-least-once: if the relay stops after publishing
+// but before marking an entry, the entry is published again.
+type OutboxRelay struct {
+	outboxCollection *mongo.Collection
+	sender           MessageSender
+	interval         time.Duration
+	batchSize        int64
+}
+
+// NewOutboxRelay creates an OutboxRelay for the outbox-collection of the
+// aggregate-collection.
+func NewOutboxRelay(
+	cfg *config.Config,
+	aggCollection *mongo.Collection,
+	sender MessageSender,
+) (*OutboxRelay, error) {
+	if sender == nil {
+		return nil, errors.New("sender cannot be nil")
+	}
+	err := cfg.Outbox.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid OutboxRelay configuration")
+		return nil, err
+	}
+	outboxColl, err := outboxCollection(aggCollection, cfg.Mongo.OutboxCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Error getting outbox-collection")
+		return nil, err
+	}
+
+	return &OutboxRelay{
+		outboxCollection: outboxColl,
+		sender:           sender,
+		interval:         time.Duration(cfg.Outbox.IntervalMS) * time.Millisecond,
+		batchSize:        int64(cfg.Outbox.BatchSize),
+	}, nil
+}
+
+// Run publishes the pending OutboxEntries at every interval, until the context is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Full batches are followed by next batch without waiting
+			for {
+				count, err := r.Relay()
+				if err != nil {
+					err = errors.Wrap(err, "OutboxRelay")
+					log.Println(err)
+				}
+				if err != nil || count < r.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Relay publishes a batch of pending OutboxEntries, and returns the number of
+// entries processed. Relaying stops at first failed entry, so the entries
+// are published in order, and the failed entry is retried by next Relay.
+func (r *OutboxRelay) Relay() (int64, error) {
+	findResults, err := r.outboxCollection.Find(
+		map[string]interface{}{
+			"status": OutboxStatusPending,
+		},
+		findopt.Sort(map[string]interface{}{
+			"createdAt": 1,
+		}),
+		findopt.Limit(r.batchSize),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding pending OutboxEntries")
+		return 0, err
+	}
+
+	for i, result := range findResults {
+		entry, assertOK := result.(*OutboxEntry)
+		if !assertOK {
+			return int64(i), errors.New("error asserting find-result to OutboxEntry")
+		}
+
+		_, _, err = r.sender.SendMessage(&sarama.ProducerMessage{
+			Topic: entry.Topic,
+			Value: sarama.StringEncoder(entry.Payload),
+		})
+		if err != nil {
+			err = errors.Wrapf(err, "Error publishing OutboxEntry %s", entry.ID.Hex())
+			r.markFailed(entry, err)
+			return int64(i), err
+		}
+
+		_, err = r.outboxCollection.UpdateMany(
+			map[string]interface{}{
+				"_id": entry.ID,
+			},
+			map[string]interface{}{
+				"status": OutboxStatusSent,
+				"sentAt": time.Now().UnixNano(),
+			},
+		)
+		if err != nil {
+			err = errors.Wrapf(
+				err, "Error marking OutboxEntry %s as sent", entry.ID.Hex(),
+			)
+			return int64(i), err
+		}
+	}
+	return int64(len(findResults)), nil
+}
+
+// markFailed records the failed publishing-attempt on the OutboxEntry,
+// which stays pending.
+func (r *OutboxRelay) markFailed(entry *OutboxEntry, sendErr error) {
+	_, err := r.outboxCollection.UpdateMany(
+		map[string]interface{}{
+			"_id": entry.ID,
+		},
+		map[string]interface{}{
+			"attempts":  entry.Attempts + 1,
+			"lastError": sendErr.Error(),
+		},
+	)
+	if err != nil {
+		err = errors.Wrapf(err, "Error updating OutboxEntry %s", entry.ID.Hex())
+		log.Println(err)
+	}
+}
@@ -0,0 +1,261 @@
+package flashsale
+
+import (
+	"encoding/json"
+
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// saleConflictResult is the Document-result when a FlashSale overlaps existing ones.
+type saleConflictResult struct {
+	ConflictingFlashSaleIDs []string `json:"conflictingFlashSaleIDs"`
+}
+
+// validateSaleWindow checks that the FlashSale's EndTime, if set, is after its StartTime.
+func validateSaleWindow(s *FlashSale) error {
+	if s.EndTime != 0 && s.EndTime <= s.StartTime {
+		return errors.New("EndTime must be after StartTime")
+	}
+	return nil
+}
+
+// validateSaleStores checks that the FlashSale's StoreIDs are not blank or duplicate.
+func validateSaleStores(s *FlashSale) error {
+	isStore := map[string]bool{}
+	for i, storeID := range s.StoreIDs {
+		if storeID == "" {
+			return errors.Errorf("blank StoreID at index %d", i)
+		}
+		if isStore[storeID] {
+			return errors.Errorf("duplicate StoreID: %s", storeID)
+		}
+		isStore[storeID] = true
+	}
+	return nil
+}
+
+// findConflictingSales returns the FlashSaleIDs of stored FlashSales which have any
+// of the ItemID/Lot pairs from provided FlashSale, during an overlapping time-window.
+// Rejected FlashSales, and the FlashSales for other Regions or StoreIDs
+// are not considered.
+// A missing StartTime or EndTime is treated as an unbounded window on that side.
+func findConflictingSales(
+	collection *mongo.Collection,
+	s *FlashSale,
+	opts ...findopt.Find,
+) ([]string, error) {
+	if len(s.Items) == 0 {
+		return []string{}, nil
+	}
+
+	itemFilters := make([]map[string]interface{}, 0)
+	for _, item := range s.Items {
+		itemFilters = append(itemFilters, map[string]interface{}{
+			"itemID": item.ItemID.String(),
+			"lot":    item.Lot,
+		})
+	}
+	filter := map[string]interface{}{
+		"flashSaleID": map[string]interface{}{
+			"$ne": s.FlashSaleID.String(),
+		},
+		"status": map[string]interface{}{
+			"$ne": StatusRejected,
+		},
+		"items": map[string]interface{}{
+			"$elemMatch": map[string]interface{}{
+				"$or": itemFilters,
+			},
+		},
+	}
+
+	andFilters := append(
+		storeScopeFilters(s.Region, s.StoreIDs),
+		overlapWindowFilters(s.StartTime, s.EndTime)...,
+	)
+	if len(andFilters) > 0 {
+		filter["$and"] = andFilters
+	}
+
+	findResults, err := collection.Find(filter, opts...)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding overlapping FlashSales")
+		return nil, err
+	}
+
+	conflictIDs := make([]string, 0)
+	for _, r := range findResults {
+		sale, assertOK := r.(*FlashSale)
+		if !assertOK {
+			err = errors.New("error asserting find-result to FlashSale")
+			return nil, err
+		}
+		conflictIDs = append(conflictIDs, sale.FlashSaleID.String())
+	}
+	return conflictIDs, nil
+}
+
+// salesOverlap checks if the FlashSales have any same ItemID/Lot pair during an
+// overlapping time-window and for overlapping stores, same as findConflictingSales
+// for FlashSales which are not stored yet.
+func salesOverlap(a *FlashSale, b *FlashSale) bool {
+	if a.Status == StatusRejected || b.Status == StatusRejected {
+		return false
+	}
+	if (a.EndTime != 0 && b.StartTime >= a.EndTime) ||
+		(b.EndTime != 0 && a.StartTime >= b.EndTime) {
+		return false
+	}
+	if a.Region != "" && b.Region != "" && a.Region != b.Region {
+		return false
+	}
+	if len(a.StoreIDs) > 0 && len(b.StoreIDs) > 0 && !sharesStore(a, b) {
+		return false
+	}
+
+	for _, aItem := range a.Items {
+		for _, bItem := range b.Items {
+			if aItem.ItemID == bItem.ItemID && aItem.Lot == bItem.Lot {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sharesStore(a *FlashSale, b *FlashSale) bool {
+	for _, aStoreID := range a.StoreIDs {
+		for _, bStoreID := range b.StoreIDs {
+			if aStoreID == bStoreID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapWindowFilters returns the filters for matching FlashSales whose time-window
+// overlaps [start, end). A 0 start or end is treated as unbounded on that side.
+func overlapWindowFilters(start int64, end int64) []map[string]interface{} {
+	windowFilters := make([]map[string]interface{}, 0)
+	if end != 0 {
+		windowFilters = append(windowFilters, map[string]interface{}{
+			"$or": []map[string]interface{}{
+				{"startTime": map[string]interface{}{"$lt": end}},
+				{"startTime": map[string]interface{}{"$exists": false}},
+			},
+		})
+	}
+	if start != 0 {
+		windowFilters = append(windowFilters, map[string]interface{}{
+			"$or": []map[string]interface{}{
+				{"endTime": map[string]interface{}{"$gt": start}},
+				{"endTime": map[string]interface{}{"$exists": false}},
+			},
+		})
+	}
+	return windowFilters
+}
+
+// storeScopeFilters returns the filters for matching FlashSales which apply to
+// any of the stores in the Region and StoreIDs. The FlashSales without Region
+// or StoreIDs apply to all Regions or stores respectively.
+func storeScopeFilters(region string, storeIDs []string) []map[string]interface{} {
+	scopeFilters := make([]map[string]interface{}, 0)
+	if region != "" {
+		scopeFilters = append(scopeFilters, map[string]interface{}{
+			"$or": []map[string]interface{}{
+				{"region": region},
+				{"region": map[string]interface{}{"$exists": false}},
+			},
+		})
+	}
+	if len(storeIDs) > 0 {
+		scopeFilters = append(scopeFilters, map[string]interface{}{
+			"$or": []map[string]interface{}{
+				{"storeIDs": map[string]interface{}{"$in": storeIDs}},
+				// Matches missing or empty StoreIDs
+				{"storeIDs.0": map[string]interface{}{"$exists": false}},
+			},
+		})
+	}
+	return scopeFilters
+}
+
+// changesSaleItems checks if the update modifies the items, time-window, or stores of
+// a FlashSale, and hence requires checking for conflicts.
+func changesSaleItems(update map[string]interface{}) bool {
+	return update["items"] != nil ||
+		update["startTime"] != nil ||
+		update["endTime"] != nil ||
+		update["region"] != nil ||
+		update["storeIDs"] != nil
+}
+
+// applySaleItemsUpdate returns a copy of FlashSale with the items, time-window, and
+// stores from provided update applied to it.
+func applySaleItemsUpdate(
+	s FlashSale,
+	update map[string]interface{},
+) (*FlashSale, error) {
+	if update["items"] != nil {
+		marshalItems, err := json.Marshal(update["items"])
+		if err != nil {
+			err = errors.Wrap(err, "Error marshalling update-items")
+			return nil, err
+		}
+		items := []soldItemXSON{}
+		err = json.Unmarshal(marshalItems, &items)
+		if err != nil {
+			err = errors.Wrap(err, "Error unmarshalling update-items")
+			return nil, err
+		}
+
+		s.Items, err = soldItemsFromXSON(items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if update["startTime"] != nil {
+		startTime, err := commonutil.AssertInt64(update["startTime"])
+		if err != nil {
+			err = errors.Wrap(err, "Error asserting StartTime")
+			return nil, err
+		}
+		s.StartTime = startTime
+	}
+	if update["endTime"] != nil {
+		endTime, err := commonutil.AssertInt64(update["endTime"])
+		if err != nil {
+			err = errors.Wrap(err, "Error asserting EndTime")
+			return nil, err
+		}
+		s.EndTime = endTime
+	}
+
+	if update["region"] != nil {
+		region, assertOK := update["region"].(string)
+		if !assertOK {
+			return nil, errors.New("Error asserting Region to string")
+		}
+		s.Region = region
+	}
+	if update["storeIDs"] != nil {
+		marshalStoreIDs, err := json.Marshal(update["storeIDs"])
+		if err != nil {
+			err = errors.Wrap(err, "Error marshalling update-storeIDs")
+			return nil, err
+		}
+		s.StoreIDs = []string{}
+		err = json.Unmarshal(marshalStoreIDs, &s.StoreIDs)
+		if err != nil {
+			err = errors.Wrap(err, "Error unmarshalling update-storeIDs")
+			return nil, err
+		}
+	}
+	return &s, nil
+}
@@ -0,0 +1,125 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store scope", func() {
+	Describe("storeScopeFilters", func() {
+		It("should not filter FlashSales applying to all stores", func() {
+			Expect(storeScopeFilters("", nil)).To(BeEmpty())
+		})
+
+		It("should match FlashSales for same Region or without Region", func() {
+			Expect(storeScopeFilters("west", nil)).To(Equal([]map[string]interface{}{
+				{
+					"$or": []map[string]interface{}{
+						{"region": "west"},
+						{"region": map[string]interface{}{"$exists": false}},
+					},
+				},
+			}))
+		})
+
+		It("should match FlashSales for any of StoreIDs or without StoreIDs", func() {
+			storeIDs := []string{"store-1", "store-2"}
+			Expect(storeScopeFilters("", storeIDs)).To(Equal([]map[string]interface{}{
+				{
+					"$or": []map[string]interface{}{
+						{"storeIDs": map[string]interface{}{"$in": storeIDs}},
+						{"storeIDs.0": map[string]interface{}{"$exists": false}},
+					},
+				},
+			}))
+		})
+	})
+
+	It("should scope SaleFilter to its store", func() {
+		f := &SaleFilter{
+			Region:  "west",
+			StoreID: "store-1",
+			From:    100,
+		}
+		andFilters := f.Query()["$and"]
+		Expect(andFilters).To(HaveLen(3))
+		Expect(andFilters).To(ContainElement(map[string]interface{}{
+			"$or": []map[string]interface{}{
+				{"storeIDs": map[string]interface{}{"$in": []string{"store-1"}}},
+				{"storeIDs.0": map[string]interface{}{"$exists": false}},
+			},
+		}))
+	})
+
+	It("should apply stores from update", func() {
+		sale, err := applySaleItemsUpdate(FlashSale{}, map[string]interface{}{
+			"region":   "west",
+			"storeIDs": []interface{}{"store-1", "store-2"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sale.Region).To(Equal("west"))
+		Expect(sale.StoreIDs).To(Equal([]string{"store-1", "store-2"}))
+	})
+
+	It("should return error for blank or duplicate StoreIDs", func() {
+		err := validateSaleStores(&FlashSale{
+			StoreIDs: []string{"store-1", ""},
+		})
+		Expect(err).To(HaveOccurred())
+
+		err = validateSaleStores(&FlashSale{
+			StoreIDs: []string{"store-1", "store-1"},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("salesOverlap", func() {
+		var a, b *FlashSale
+
+		BeforeEach(func() {
+			itemID, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			a = &FlashSale{
+				Items:     []SoldItem{SoldItem{ItemID: itemID, Lot: "lot-1"}},
+				StartTime: 100,
+				EndTime:   200,
+				StoreIDs:  []string{"store-1"},
+			}
+			b = &FlashSale{
+				Items:     []SoldItem{SoldItem{ItemID: itemID, Lot: "lot-1"}},
+				StartTime: 150,
+				StoreIDs:  []string{"store-1", "store-2"},
+			}
+		})
+
+		It("should overlap for same item-lot, time-window and store", func() {
+			Expect(salesOverlap(a, b)).To(BeTrue())
+			Expect(salesOverlap(b, a)).To(BeTrue())
+		})
+
+		It("should not overlap for adjacent time-windows", func() {
+			b.StartTime = 200
+			Expect(salesOverlap(a, b)).To(BeFalse())
+		})
+
+		It("should not overlap for other lots, stores or Regions", func() {
+			b.Items[0].Lot = "lot-2"
+			Expect(salesOverlap(a, b)).To(BeFalse())
+
+			b.Items[0].Lot = "lot-1"
+			b.StoreIDs = []string{"store-2"}
+			Expect(salesOverlap(a, b)).To(BeFalse())
+
+			b.StoreIDs = nil
+			a.Region = "west"
+			b.Region = "east"
+			Expect(salesOverlap(a, b)).To(BeFalse())
+		})
+
+		It("should not overlap rejected FlashSales", func() {
+			b.Status = StatusRejected
+			Expect(salesOverlap(a, b)).To(BeFalse())
+		})
+	})
+})
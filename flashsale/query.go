@@ -0,0 +1,104 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// historyRequest is the Event-data for querying the change-history of a FlashSale.
+type historyRequest struct {
+	FlashSaleID string `json:"flashSaleID"`
+}
+
+// flashSaleHistory returns the AuditRecords of a FlashSale, oldest first.
+func flashSaleHistory(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	req := &historyRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err == nil {
+		_, err = uuuid.FromString(req.FlashSaleID)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "History: Error parsing FlashSaleID")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
+	if err != nil {
+		err = errors.Wrap(err, "History: Error getting audit-collection")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	findResults, err := auditColl.Find(
+		map[string]interface{}{
+			"flashSaleID": req.FlashSaleID,
+		},
+		findopt.Sort(map[string]interface{}{
+			"timestamp": 1,
+		}),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "History: Error finding AuditRecords")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(findResults)
+	if err != nil {
+		err = errors.Wrap(err, "History: Error marshalling AuditRecords")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
@@ -0,0 +1,315 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// defaultQueryLimit is the number of FlashSales returned if no limit is provided.
+const defaultQueryLimit = 20
+
+// sortableFields are the FlashSale-fields which query-results can be sorted by.
+var sortableFields = map[string]bool{
+	"endTime":     true,
+	"flashSaleID": true,
+	"startTime":   true,
+	"timestamp":   true,
+}
+
+// saleQueryRequest is the Event-data for querying FlashSales.
+type saleQueryRequest struct {
+	SaleFilter
+	FlashSaleID string `json:"flashSaleID,omitempty"`
+	// At is the Unix-time at which the FlashSales are active.
+	// Defaults to current time.
+	At int64 `json:"at,omitempty"`
+
+	Skip  int64 `json:"skip,omitempty"`
+	Limit int64 `json:"limit,omitempty"`
+	// SortBy defaults to "startTime". SortOrder is 1 for ascending (default),
+	// and -1 for descending.
+	SortBy    string `json:"sortBy,omitempty"`
+	SortOrder int32  `json:"sortOrder,omitempty"`
+}
+
+// saleQueryResult is the result of listing FlashSales.
+type saleQueryResult struct {
+	FlashSales []*FlashSale `json:"flashSales"`
+	Skip       int64        `json:"skip"`
+	Limit      int64        `json:"limit"`
+	// HasMore is true if more FlashSales match the query after this page.
+	HasMore bool `json:"hasMore"`
+}
+
+// flashSaleByID returns the FlashSale with the FlashSaleID.
+func flashSaleByID(
+	_ *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	req := &saleQueryRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err == nil {
+		_, err = uuuid.FromString(req.FlashSaleID)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "QueryByID: Error parsing FlashSaleID")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	findResults, err := collection.Find(
+		map[string]interface{}{
+			"flashSaleID": req.FlashSaleID,
+		},
+		findopt.Limit(1),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "QueryByID: Error finding FlashSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if len(findResults) == 0 {
+		err = errors.New("flashSale not found")
+		err = errors.Wrap(err, "QueryByID")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(findResults[0])
+	if err != nil {
+		err = errors.Wrap(err, "QueryByID: Error marshalling FlashSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// activeFlashSales lists the active FlashSales whose time-window contains
+// the requested time, optionally for the requested Region or store.
+func activeFlashSales(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return listFlashSales(c, collection, event, prepareActiveQuery)
+}
+
+// prepareActiveQuery sets the SaleFilter for FlashSales active at the requested
+// time, or at current time.
+func prepareActiveQuery(req *saleQueryRequest) error {
+	at := req.At
+	if at == 0 {
+		at = time.Now().Unix()
+	}
+	req.SaleFilter = SaleFilter{
+		From:    at,
+		To:      at + 1,
+		Status:  StatusActive,
+		Region:  req.Region,
+		StoreID: req.StoreID,
+	}
+	return nil
+}
+
+// flashSalesByItem lists the FlashSales having an item matching the
+// requested ItemID, SKU, UPC, and Lot.
+func flashSalesByItem(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return listFlashSales(c, collection, event, prepareItemQuery)
+}
+
+// prepareItemQuery checks that the request has at least one item-field.
+func prepareItemQuery(req *saleQueryRequest) error {
+	f := req.SaleFilter
+	if f.ItemID == "" && f.SKU == "" && f.UPC == "" && f.Lot == "" {
+		return errors.New("one of itemID, sku, upc, or lot is required")
+	}
+	return nil
+}
+
+// listFlashSales lists a page of FlashSales matching the SaleFilter of the request.
+// The prepare func validates the request and sets its SaleFilter.
+func listFlashSales(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+	prepare func(*saleQueryRequest) error,
+) *model.Document {
+	req := &saleQueryRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err == nil {
+		err = prepare(req)
+	}
+	if err == nil {
+		err = validatePaging(req, int64(c.cfg.Results.MaxReturnDocuments))
+	}
+	if err != nil {
+		err = errors.Wrap(err, "QueryList: Error in query-request")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	// One extra FlashSale is fetched to check if there are more FlashSales
+	findResults, err := collection.Find(
+		req.SaleFilter.Query(),
+		findopt.Sort(bson.NewDocument(
+			bson.EC.Int32(req.SortBy, req.SortOrder),
+			bson.EC.Int32("_id", req.SortOrder),
+		)),
+		findopt.Skip(req.Skip),
+		findopt.Limit(req.Limit+1),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "QueryList: Error finding FlashSales")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	queryResult := &saleQueryResult{
+		FlashSales: make([]*FlashSale, 0),
+		Skip:       req.Skip,
+		Limit:      req.Limit,
+	}
+	for i, r := range findResults {
+		if int64(i) == req.Limit {
+			queryResult.HasMore = true
+			break
+		}
+		sale, assertOK := r.(*FlashSale)
+		if !assertOK {
+			err = errors.New("QueryList: Error asserting find-result to FlashSale")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     InternalError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+		queryResult.FlashSales = append(queryResult.FlashSales, sale)
+	}
+
+	result, err := json.Marshal(queryResult)
+	if err != nil {
+		err = errors.Wrap(err, "QueryList: Error marshalling FlashSales")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// validatePaging validates the paging and sorting of request, and sets the defaults.
+// The limit is capped to the maximum of returned FlashSales.
+func validatePaging(req *saleQueryRequest, max int64) error {
+	if req.Skip < 0 {
+		return errors.New("skip cannot be negative")
+	}
+	if req.Limit < 0 {
+		return errors.New("limit cannot be negative")
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultQueryLimit
+	}
+	if req.Limit > max {
+		req.Limit = max
+	}
+
+	if req.SortBy == "" {
+		req.SortBy = "startTime"
+	}
+	if !sortableFields[req.SortBy] {
+		return errors.Errorf("unsupported sortBy: %s", req.SortBy)
+	}
+	if req.SortOrder == 0 {
+		req.SortOrder = 1
+	}
+	if req.SortOrder != 1 && req.SortOrder != -1 {
+		return errors.New("sortOrder must be 1 or -1")
+	}
+	return nil
+}
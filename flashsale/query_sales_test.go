@@ -0,0 +1,168 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validatePaging", func() {
+	const max = 100
+
+	It("should set defaults for paging and sorting", func() {
+		req := &saleQueryRequest{}
+		err := validatePaging(req, max)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Limit).To(Equal(int64(defaultQueryLimit)))
+		Expect(req.SortBy).To(Equal("startTime"))
+		Expect(req.SortOrder).To(Equal(int32(1)))
+	})
+
+	It("should cap limit to maximum of returned FlashSales", func() {
+		req := &saleQueryRequest{
+			Limit: max + 1,
+		}
+		err := validatePaging(req, max)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Limit).To(Equal(int64(max)))
+	})
+
+	It("should return error if sortBy is not sortable", func() {
+		req := &saleQueryRequest{
+			SortBy: "items",
+		}
+		err := validatePaging(req, max)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if sortOrder is invalid", func() {
+		req := &saleQueryRequest{
+			SortOrder: 2,
+		}
+		err := validatePaging(req, max)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if skip is negative", func() {
+		req := &saleQueryRequest{
+			Skip: -1,
+		}
+		err := validatePaging(req, max)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Query handlers", func() {
+	var c *ExecContext
+
+	// queryEvent returns a "query" Event with provided data.
+	queryEvent := func(serviceAction string, data string) *model.Event {
+		uuid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		cid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return &model.Event{
+			AggregateID:   AggregateID,
+			CorrelationID: cid,
+			Data:          []byte(data),
+			EventAction:   "query",
+			ServiceAction: serviceAction,
+			UUID:          uuid,
+		}
+	}
+
+	// expectRequestError checks that the handler rejected the request without
+	// reading the (nil) collection.
+	expectRequestError := func(doc *model.Document, event *model.Event) {
+		Expect(doc).ToNot(BeNil())
+		Expect(doc.Error).ToNot(BeEmpty())
+		Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+		Expect(doc.CorrelationID).To(Equal(event.CorrelationID))
+		Expect(doc.ServiceAction).To(Equal(event.ServiceAction))
+		Expect(doc.UUID).To(Equal(event.UUID))
+	}
+
+	BeforeEach(func() {
+		c = NewExecContext(config.Default())
+	})
+
+	Describe("flashSaleByID", func() {
+		It("should return error for invalid request-data", func() {
+			event := queryEvent("flashSaleByID", "{")
+			expectRequestError(flashSaleByID(c, nil, event), event)
+		})
+
+		It("should return error for missing or invalid FlashSaleID", func() {
+			for _, data := range []string{`{}`, `{"flashSaleID": "sale-1"}`} {
+				event := queryEvent("flashSaleByID", data)
+				expectRequestError(flashSaleByID(c, nil, event), event)
+			}
+		})
+	})
+
+	Describe("activeFlashSales", func() {
+		It("should select FlashSales active at requested time", func() {
+			req := &saleQueryRequest{
+				At: 1541250000,
+				SaleFilter: SaleFilter{
+					Status:  StatusDraft,
+					ItemID:  "item-1",
+					Region:  "west",
+					StoreID: "store-1",
+				},
+			}
+			Expect(prepareActiveQuery(req)).To(Succeed())
+			Expect(req.SaleFilter).To(Equal(SaleFilter{
+				From:    1541250000,
+				To:      1541250001,
+				Status:  StatusActive,
+				Region:  "west",
+				StoreID: "store-1",
+			}))
+		})
+
+		It("should default to FlashSales active at current time", func() {
+			req := &saleQueryRequest{}
+			Expect(prepareActiveQuery(req)).To(Succeed())
+			Expect(req.From).ToNot(BeZero())
+			Expect(req.To).To(Equal(req.From + 1))
+		})
+
+		It("should return error for invalid paging", func() {
+			for _, data := range []string{
+				"{",
+				`{"sortBy": "items"}`,
+				`{"sortOrder": 2}`,
+				`{"skip": -1}`,
+			} {
+				event := queryEvent("activeFlashSales", data)
+				expectRequestError(activeFlashSales(c, nil, event), event)
+			}
+		})
+	})
+
+	Describe("flashSalesByItem", func() {
+		It("should require an item-field", func() {
+			Expect(prepareItemQuery(&saleQueryRequest{})).ToNot(Succeed())
+
+			req := &saleQueryRequest{
+				SaleFilter: SaleFilter{Lot: "lot-1"},
+			}
+			Expect(prepareItemQuery(req)).To(Succeed())
+		})
+
+		It("should return error for requests without item-fields", func() {
+			for _, data := range []string{`{}`, `{"region": "west"}`} {
+				event := queryEvent("flashSalesByItem", data)
+				expectRequestError(flashSalesByItem(c, nil, event), event)
+			}
+		})
+
+		It("should return error for invalid paging", func() {
+			event := queryEvent("flashSalesByItem", `{"sku": "sku-1", "skip": -1}`)
+			expectRequestError(flashSalesByItem(c, nil, event), event)
+		})
+	})
+})
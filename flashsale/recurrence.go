@@ -0,0 +1,390 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// RecurringSale defines a FlashSale which repeats by its RecurrenceRule.
+// The RecurrenceScheduler creates a FlashSale for each occurrence ahead of its start.
+type RecurringSale struct {
+	ID           objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	RecurrenceID uuuid.UUID        `bson:"recurrenceID,omitempty" json:"recurrenceID,omitempty"`
+	Items        []SoldItem        `bson:"items,omitempty" json:"items,omitempty"`
+	Region       string            `bson:"region,omitempty" json:"region,omitempty"`
+	StoreIDs     []string          `bson:"storeIDs,omitempty" json:"storeIDs,omitempty"`
+	TimeZone     string            `bson:"timeZone,omitempty" json:"timeZone,omitempty"`
+	// Status is the Status of created FlashSales, StatusActive (default)
+	// or StatusDraft.
+	Status string         `bson:"status,omitempty" json:"status,omitempty"`
+	Rule   RecurrenceRule `bson:"rule,omitempty" json:"rule,omitempty"`
+	// Skipped are the Keys of occurrences for which no FlashSale is created.
+	Skipped []string `bson:"skipped,omitempty" json:"skipped,omitempty"`
+	// Overrides modify single occurrences.
+	Overrides []OccurrenceOverride `bson:"overrides,omitempty" json:"overrides,omitempty"`
+	// LastOccurrence is the Key of the latest occurrence created as FlashSale.
+	LastOccurrence string `bson:"lastOccurrence,omitempty" json:"lastOccurrence,omitempty"`
+	// OccurrenceCount is the number of occurrences up to LastOccurrence,
+	// including the skipped occurrences.
+	OccurrenceCount int `bson:"occurrenceCount,omitempty" json:"occurrenceCount,omitempty"`
+	// Complete is set once all occurrences are created as FlashSales.
+	Complete  bool  `bson:"complete,omitempty" json:"complete,omitempty"`
+	Timestamp int64 `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	// Revision is incremented on each change to Skipped or Overrides, so
+	// concurrent changes are detected.
+	Revision int64 `bson:"revision,omitempty" json:"revision,omitempty"`
+}
+
+// OccurrenceOverride modifies a single occurrence of a RecurringSale.
+// The fields which are set replace the values from RecurringSale.
+type OccurrenceOverride struct {
+	// Occurrence is the Key (local start as defined by the rule) of occurrence.
+	Occurrence string `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	// Start is the local start of occurrence.
+	Start    string     `bson:"start,omitempty" json:"start,omitempty"`
+	Duration int64      `bson:"duration,omitempty" json:"duration,omitempty"`
+	Items    []SoldItem `bson:"items,omitempty" json:"items,omitempty"`
+}
+
+// Same reasons as flashSaleBSON
+type recurringSaleBSON struct {
+	ID              objectid.ObjectID        `bson:"_id,omitempty"`
+	RecurrenceID    string                   `bson:"recurrenceID,omitempty"`
+	Items           []soldItemXSON           `bson:"items,omitempty"`
+	Region          string                   `bson:"region,omitempty"`
+	StoreIDs        []string                 `bson:"storeIDs,omitempty"`
+	TimeZone        string                   `bson:"timeZone,omitempty"`
+	Status          string                   `bson:"status,omitempty"`
+	Rule            RecurrenceRule           `bson:"rule,omitempty"`
+	Skipped         []string                 `bson:"skipped,omitempty"`
+	Overrides       []occurrenceOverrideXSON `bson:"overrides,omitempty"`
+	LastOccurrence  string                   `bson:"lastOccurrence,omitempty"`
+	OccurrenceCount int                      `bson:"occurrenceCount,omitempty"`
+	Complete        bool                     `bson:"complete,omitempty"`
+	Timestamp       int64                    `bson:"timestamp,omitempty"`
+	Revision        int64                    `bson:"revision,omitempty"`
+}
+
+type recurringSaleJSON struct {
+	ID              string                   `json:"_id,omitempty"`
+	RecurrenceID    string                   `json:"recurrenceID,omitempty"`
+	Items           []soldItemXSON           `json:"items,omitempty"`
+	Region          string                   `json:"region,omitempty"`
+	StoreIDs        []string                 `json:"storeIDs,omitempty"`
+	TimeZone        string                   `json:"timeZone,omitempty"`
+	Status          string                   `json:"status,omitempty"`
+	Rule            RecurrenceRule           `json:"rule,omitempty"`
+	Skipped         []string                 `json:"skipped,omitempty"`
+	Overrides       []occurrenceOverrideXSON `json:"overrides,omitempty"`
+	LastOccurrence  string                   `json:"lastOccurrence,omitempty"`
+	OccurrenceCount int                      `json:"occurrenceCount,omitempty"`
+	Complete        bool                     `json:"complete,omitempty"`
+	Timestamp       int64                    `json:"timestamp,omitempty"`
+	Revision        int64                    `json:"revision,omitempty"`
+}
+
+type occurrenceOverrideXSON struct {
+	Occurrence string         `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	Start      string         `bson:"start,omitempty" json:"start,omitempty"`
+	Duration   int64          `bson:"duration,omitempty" json:"duration,omitempty"`
+	Items      []soldItemXSON `bson:"items,omitempty" json:"items,omitempty"`
+}
+
+// recurrenceCollection returns the collection with provided name storing
+// RecurringSales.
+func recurrenceCollection(
+	aggCollection *mongo.Collection,
+	name string,
+) (*mongo.Collection, error) {
+	indexConfigs := []mongo.IndexConfig{
+		mongo.IndexConfig{
+			ColumnConfig: []mongo.IndexColumnConfig{
+				mongo.IndexColumnConfig{
+					Name: "recurrenceID",
+				},
+			},
+			IsUnique: true,
+			Name:     "recurrenceID_index",
+		},
+	}
+	return siblingCollection(aggCollection, name, &RecurringSale{}, indexConfigs)
+}
+
+// occurrenceSaleID returns the FlashSaleID for an occurrence of RecurringSale.
+// The FlashSaleID is derived from RecurrenceID and occurrence-Key (as a version-5
+// UUID), so an occurrence is only created once.
+func occurrenceSaleID(recurrenceID uuuid.UUID, key string) uuuid.UUID {
+	return derivedUUID(recurrenceID, key)
+}
+
+// override returns the OccurrenceOverride for the occurrence-Key, if any.
+func (r *RecurringSale) override(key string) *OccurrenceOverride {
+	for i := range r.Overrides {
+		if r.Overrides[i].Occurrence == key {
+			return &r.Overrides[i]
+		}
+	}
+	return nil
+}
+
+// isSkipped checks if the occurrence-Key is skipped.
+func (r *RecurringSale) isSkipped(key string) bool {
+	for _, skipped := range r.Skipped {
+		if skipped == key {
+			return true
+		}
+	}
+	return false
+}
+
+// lastOccurrenceTime returns the wall-clock time of LastOccurrence, or zero
+// time if no occurrence is created yet.
+func (r *RecurringSale) lastOccurrenceTime() (time.Time, error) {
+	if r.LastOccurrence == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(localTimeLayout, r.LastOccurrence)
+	if err != nil {
+		err = errors.Wrap(err, "invalid LastOccurrence")
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// occurrenceSale returns the FlashSale for the occurrence, with the
+// OccurrenceOverride applied.
+func (r *RecurringSale) occurrenceSale(
+	o occurrence,
+	loc *time.Location,
+	now time.Time,
+) (*FlashSale, error) {
+	start := o.Start
+	duration := r.Rule.Duration
+	items := r.Items
+	if override := r.override(o.Key); override != nil {
+		if override.Start != "" {
+			var err error
+			start, err = time.Parse(localTimeLayout, override.Start)
+			if err != nil {
+				return nil, errors.Errorf(
+					"invalid Start in override: %s, must be of format %s",
+					override.Start, localTimeLayout,
+				)
+			}
+		}
+		if override.Duration != 0 {
+			duration = override.Duration
+		}
+		if len(override.Items) > 0 {
+			items = override.Items
+		}
+	}
+
+	// The end is computed on the wall-clock, so an occurrence spanning a
+	// DST-transition ends at its usual local time
+	end := start.Add(time.Duration(duration) * time.Second)
+	status := r.Status
+	if status == "" {
+		status = StatusActive
+	}
+	return &FlashSale{
+		FlashSaleID: occurrenceSaleID(r.RecurrenceID, o.Key),
+		Items:       items,
+		StartTime:   resolveOccurrenceTime(start, loc).Unix(),
+		EndTime:     resolveOccurrenceTime(end, loc).Unix(),
+		Status:      status,
+		Timestamp:   now.Unix(),
+		Region:      r.Region,
+		StoreIDs:    r.StoreIDs,
+		TimeZone:    r.TimeZone,
+	}, nil
+}
+
+func overridesToMaps(overrides []OccurrenceOverride) []map[string]interface{} {
+	maps := make([]map[string]interface{}, 0)
+	for _, o := range overrides {
+		m := map[string]interface{}{
+			"occurrence": o.Occurrence,
+		}
+		if o.Start != "" {
+			m["start"] = o.Start
+		}
+		if o.Duration != 0 {
+			m["duration"] = o.Duration
+		}
+		if len(o.Items) > 0 {
+			m["items"] = soldItemsToMaps(o.Items)
+		}
+		maps = append(maps, m)
+	}
+	return maps
+}
+
+func overridesFromXSON(
+	xsonOverrides []occurrenceOverrideXSON,
+) ([]OccurrenceOverride, error) {
+	overrides := make([]OccurrenceOverride, 0)
+	for _, o := range xsonOverrides {
+		items, err := soldItemsFromXSON(o.Items)
+		if err != nil {
+			err = errors.Wrapf(err, "Error in override for %s", o.Occurrence)
+			return nil, err
+		}
+		overrides = append(overrides, OccurrenceOverride{
+			Occurrence: o.Occurrence,
+			Start:      o.Start,
+			Duration:   o.Duration,
+			Items:      items,
+		})
+	}
+	return overrides, nil
+}
+
+// toMap converts the RecurringSale to map for marshalling.
+func (r *RecurringSale) toMap() map[string]interface{} {
+	in := map[string]interface{}{
+		"status":    r.Status,
+		"rule":      r.Rule,
+		"complete":  r.Complete,
+		"timestamp": r.Timestamp,
+		"revision":  r.Revision,
+	}
+	if r.RecurrenceID != (uuuid.UUID{}) {
+		in["recurrenceID"] = r.RecurrenceID.String()
+	}
+	if len(r.Items) > 0 {
+		in["items"] = soldItemsToMaps(r.Items)
+	}
+	if r.Region != "" {
+		in["region"] = r.Region
+	}
+	if len(r.StoreIDs) > 0 {
+		in["storeIDs"] = r.StoreIDs
+	}
+	if r.TimeZone != "" {
+		in["timeZone"] = r.TimeZone
+	}
+	if len(r.Skipped) > 0 {
+		in["skipped"] = r.Skipped
+	}
+	if len(r.Overrides) > 0 {
+		in["overrides"] = overridesToMaps(r.Overrides)
+	}
+	if r.LastOccurrence != "" {
+		in["lastOccurrence"] = r.LastOccurrence
+	}
+	if r.OccurrenceCount > 0 {
+		in["occurrenceCount"] = r.OccurrenceCount
+	}
+	return in
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (r RecurringSale) MarshalBSON() ([]byte, error) {
+	in := r.toMap()
+	if r.ID != objectid.NilObjectID {
+		in["_id"] = r.ID
+	}
+	return bson.Marshal(in)
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (r *RecurringSale) MarshalJSON() ([]byte, error) {
+	in := r.toMap()
+	if r.ID != objectid.NilObjectID {
+		in["_id"] = r.ID.Hex()
+	}
+	return json.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (r *RecurringSale) UnmarshalBSON(in []byte) error {
+	rb := &recurringSaleBSON{}
+	err := bson.Unmarshal(in, rb)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error")
+		return err
+	}
+
+	r.ID = rb.ID
+	r.Region = rb.Region
+	r.StoreIDs = rb.StoreIDs
+	r.TimeZone = rb.TimeZone
+	r.Status = rb.Status
+	r.Rule = rb.Rule
+	r.Skipped = rb.Skipped
+	r.LastOccurrence = rb.LastOccurrence
+	r.OccurrenceCount = rb.OccurrenceCount
+	r.Complete = rb.Complete
+	r.Timestamp = rb.Timestamp
+	r.Revision = rb.Revision
+
+	r.RecurrenceID, err = uuuid.FromString(rb.RecurrenceID)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing RecurrenceID")
+		return err
+	}
+	r.Items, err = soldItemsFromXSON(rb.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON")
+		return err
+	}
+	r.Overrides, err = overridesFromXSON(rb.Overrides)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON")
+		return err
+	}
+	return nil
+}
+
+// UnmarshalJSON returns JSON-type from bytes.
+func (r *RecurringSale) UnmarshalJSON(in []byte) error {
+	rj := &recurringSaleJSON{}
+	err := json.Unmarshal(in, rj)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON Error")
+		return err
+	}
+
+	r.Region = rj.Region
+	r.StoreIDs = rj.StoreIDs
+	r.TimeZone = rj.TimeZone
+	r.Status = rj.Status
+	r.Rule = rj.Rule
+	r.Skipped = rj.Skipped
+	r.LastOccurrence = rj.LastOccurrence
+	r.OccurrenceCount = rj.OccurrenceCount
+	r.Complete = rj.Complete
+	r.Timestamp = rj.Timestamp
+	r.Revision = rj.Revision
+
+	if rj.ID != "" && rj.ID != objectid.NilObjectID.String() {
+		r.ID, err = objectid.FromHex(rj.ID)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing ObjectID")
+			return err
+		}
+	}
+	r.RecurrenceID, err = uuuid.FromString(rj.RecurrenceID)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing RecurrenceID")
+		return err
+	}
+	r.Items, err = soldItemsFromXSON(rj.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON")
+		return err
+	}
+	r.Overrides, err = overridesFromXSON(rj.Overrides)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// recurringSaleCreated stores a RecurringSale. Its occurrences are created as
+// FlashSales by the RecurrenceScheduler.
+func recurringSaleCreated(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	recurringSale := &RecurringSale{}
+	err := json.Unmarshal(event.Data, recurringSale)
+	if err != nil {
+		err = errors.Wrap(
+			err, "RecurringSaleCreated: Error while unmarshalling Event-data",
+		)
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	// Progress of the scheduler is not set by Events
+	recurringSale.LastOccurrence = ""
+	recurringSale.OccurrenceCount = 0
+	recurringSale.Complete = false
+	recurringSale.Revision = 0
+	err = validateRecurringSale(recurringSale)
+	if err != nil {
+		err = errors.Wrap(err, "RecurringSaleCreated")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	recColl, err := recurrenceCollection(collection, c.cfg.Mongo.RecurrenceCollection)
+	if err != nil {
+		err = errors.Wrap(err, "RecurringSaleCreated")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	_, err = recColl.InsertOne(recurringSale)
+	if err != nil {
+		err = errors.Wrap(
+			err, "RecurringSaleCreated: Error Inserting RecurringSale into Database",
+		)
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(recurringSale)
+	if err != nil {
+		err = errors.Wrap(err, "RecurringSaleCreated: Error marshalling RecurringSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// validateRecurringSale checks that the RecurringSale has a valid RecurrenceRule,
+// and that its occurrences are valid FlashSales, with the overrides applied.
+func validateRecurringSale(r *RecurringSale) error {
+	if r.RecurrenceID == (uuuid.UUID{}) {
+		return errors.New("missing RecurrenceID")
+	}
+	if r.Timestamp == 0 {
+		return errors.New("missing Timestamp")
+	}
+	if r.Status != "" && r.Status != StatusActive && r.Status != StatusDraft {
+		return errors.Errorf("invalid Status: %s", r.Status)
+	}
+	loc, err := loadTimeZone(r.TimeZone)
+	if err != nil {
+		return err
+	}
+	schedule, err := r.Rule.schedule()
+	if err != nil {
+		err = errors.Wrap(err, "invalid RecurrenceRule")
+		return err
+	}
+
+	// The first occurrence is validated as representative of all occurrences
+	first := schedule.occurrencesAfter(
+		time.Time{}, 0, schedule.start.AddDate(1, 0, 0), 1,
+	)
+	if len(first) == 0 {
+		return errors.New("RecurrenceRule has no occurrences within a year of Start")
+	}
+	keys := []string{first[0].Key}
+	for _, o := range r.Overrides {
+		keys = append(keys, o.Occurrence)
+	}
+	for _, key := range keys {
+		err = validateOccurrence(r, schedule, loc, key)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range r.Skipped {
+		_, err = schedule.findOccurrence(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOccurrence checks that the occurrence with provided Key is part of
+// RecurrenceRule, and is a valid FlashSale.
+func validateOccurrence(
+	r *RecurringSale,
+	schedule *recurrenceSchedule,
+	loc *time.Location,
+	key string,
+) error {
+	o, err := schedule.findOccurrence(key)
+	if err != nil {
+		return err
+	}
+	flashSale, err := r.occurrenceSale(o, loc, time.Now())
+	if err != nil {
+		return err
+	}
+	err = ValidateFlashSale(flashSale)
+	if err != nil {
+		err = errors.Wrapf(err, "invalid occurrence %s", key)
+		return err
+	}
+	return nil
+}
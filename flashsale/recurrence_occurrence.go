@@ -0,0 +1,296 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// occurrenceRequest is the Event-data for skipping or modifying a single
+// occurrence of a RecurringSale. The optional fields modify the occurrence.
+type occurrenceRequest struct {
+	RecurrenceID string `json:"recurrenceID"`
+	// Occurrence is the Key (local start as defined by the rule) of occurrence.
+	Occurrence string         `json:"occurrence"`
+	Start      string         `json:"start,omitempty"`
+	Duration   int64          `json:"duration,omitempty"`
+	Items      []soldItemXSON `json:"items,omitempty"`
+}
+
+// recurringSaleOccurrenceSkipped skips a single occurrence of a RecurringSale,
+// so no FlashSale is created for it.
+func recurringSaleOccurrenceSkipped(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return c.changeOccurrence(
+		collection, event,
+		func(r *RecurringSale, req *occurrenceRequest) error {
+			if !r.isSkipped(req.Occurrence) {
+				r.Skipped = append(r.Skipped, req.Occurrence)
+			}
+			return nil
+		},
+	)
+}
+
+// recurringSaleOccurrenceModified modifies the start, duration, or items of a
+// single occurrence of a RecurringSale. A skipped occurrence is no longer skipped.
+func recurringSaleOccurrenceModified(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	return c.changeOccurrence(
+		collection, event,
+		func(r *RecurringSale, req *occurrenceRequest) error {
+			items, err := soldItemsFromXSON(req.Items)
+			if err != nil {
+				return err
+			}
+			override := OccurrenceOverride{
+				Occurrence: req.Occurrence,
+				Start:      req.Start,
+				Duration:   req.Duration,
+				Items:      items,
+			}
+			if existing := r.override(req.Occurrence); existing != nil {
+				*existing = override
+			} else {
+				r.Overrides = append(r.Overrides, override)
+			}
+
+			skipped := make([]string, 0)
+			for _, key := range r.Skipped {
+				if key != req.Occurrence {
+					skipped = append(skipped, key)
+				}
+			}
+			r.Skipped = skipped
+			return nil
+		},
+	)
+}
+
+// changeOccurrence applies the change to an occurrence of RecurringSale which is
+// yet to be created as FlashSale. The FlashSales of created occurrences are
+// changed like any other FlashSale.
+func (c *ExecContext) changeOccurrence(
+	collection *mongo.Collection,
+	event *model.Event,
+	change func(r *RecurringSale, req *occurrenceRequest) error,
+) *model.Document {
+	req := &occurrenceRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if req.RecurrenceID == "" {
+		err = errors.New("missing RecurrenceID")
+	} else if req.Occurrence == "" {
+		err = errors.New("missing Occurrence")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	recColl, err := recurrenceCollection(collection, c.cfg.Mongo.RecurrenceCollection)
+	var findResult interface{}
+	if err == nil {
+		findResult, err = recColl.FindOne(map[string]interface{}{
+			"recurrenceID": req.RecurrenceID,
+		})
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence: Error finding RecurringSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	recurringSale, assertOK := findResult.(*RecurringSale)
+	if !assertOK {
+		err = errors.New("error asserting find-result to RecurringSale")
+		err = errors.Wrap(err, "Occurrence")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	err = changeRecurringSale(recurringSale, req, change)
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	// Only the changed fields are set, so the progress of
+	// RecurrenceScheduler is not overwritten
+	updateResult, err := recColl.UpdateMany(
+		occurrenceGuard(recurringSale),
+		map[string]interface{}{
+			"skipped":   recurringSale.Skipped,
+			"overrides": overridesToMaps(recurringSale.Overrides),
+			"revision":  recurringSale.Revision + 1,
+		},
+	)
+	if err == nil && updateResult.MatchedCount == 0 {
+		err = errors.New(
+			"RecurringSale was changed while applying the change, retry the change",
+		)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence: Error updating RecurringSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(recurringSale)
+	if err != nil {
+		err = errors.Wrap(err, "Occurrence: Error marshalling RecurringSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// occurrenceGuard returns the filter matching the RecurringSale only if its
+// occurrences were not changed, and no more occurrences were created by
+// RecurrenceScheduler since it was read.
+func occurrenceGuard(r *RecurringSale) map[string]interface{} {
+	filter := map[string]interface{}{
+		"recurrenceID": r.RecurrenceID.String(),
+		"revision":     r.Revision,
+		"lastOccurrence": map[string]interface{}{
+			"$exists": false,
+		},
+	}
+	if r.LastOccurrence != "" {
+		filter["lastOccurrence"] = r.LastOccurrence
+	}
+	return filter
+}
+
+// changeRecurringSale applies the change to the occurrence in request, and
+// validates the resulting occurrence.
+func changeRecurringSale(
+	r *RecurringSale,
+	req *occurrenceRequest,
+	change func(r *RecurringSale, req *occurrenceRequest) error,
+) error {
+	loc, err := loadTimeZone(r.TimeZone)
+	if err != nil {
+		return err
+	}
+	schedule, err := r.Rule.schedule()
+	if err != nil {
+		err = errors.Wrap(err, "invalid RecurrenceRule")
+		return err
+	}
+	if r.LastOccurrence != "" && req.Occurrence <= r.LastOccurrence {
+		_, err = schedule.findOccurrence(req.Occurrence)
+		if err != nil {
+			return err
+		}
+		return errors.Errorf(
+			"occurrence %s is already created as FlashSale %s, "+
+				"update or delete the FlashSale instead",
+			req.Occurrence, occurrenceSaleID(r.RecurrenceID, req.Occurrence),
+		)
+	}
+	after, err := r.lastOccurrenceTime()
+	if err != nil {
+		return err
+	}
+	o, err := schedule.findOccurrenceAfter(req.Occurrence, after, r.OccurrenceCount)
+	if err != nil {
+		return err
+	}
+
+	err = change(r, req)
+	if err != nil {
+		return err
+	}
+	if r.isSkipped(o.Key) {
+		return nil
+	}
+	flashSale, err := r.occurrenceSale(o, loc, time.Now())
+	if err != nil {
+		return err
+	}
+	err = ValidateFlashSale(flashSale)
+	if err != nil {
+		err = errors.Wrapf(err, "invalid occurrence %s", o.Key)
+		return err
+	}
+	return nil
+}
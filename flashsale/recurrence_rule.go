@@ -0,0 +1,446 @@
+package flashsale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FrequencyDaily repeats a RecurringSale every Interval days.
+const FrequencyDaily = "daily"
+
+// FrequencyWeekly repeats a RecurringSale on the Weekdays of every Interval weeks.
+const FrequencyWeekly = "weekly"
+
+// FrequencyCron repeats a RecurringSale at the times matching the Cron-expression.
+const FrequencyCron = "cron"
+
+// maxRecurrenceInterval limits the Interval of a RecurrenceRule, so the
+// occurrences of a rule can be enumerated day-by-day.
+const maxRecurrenceInterval = 366
+
+// RecurrenceRule defines when the occurrences of a RecurringSale start.
+// All times are local times ("2006-01-02T15:04:05") in the TimeZone of the
+// RecurringSale, so the occurrences start at the same wall-clock time
+// across DST-transitions.
+type RecurrenceRule struct {
+	// Frequency is one of "daily", "weekly" or "cron".
+	Frequency string `bson:"frequency,omitempty" json:"frequency,omitempty"`
+	// Interval repeats "daily" and "weekly" rules every Interval days or weeks.
+	// Default is 1.
+	Interval int `bson:"interval,omitempty" json:"interval,omitempty"`
+	// Weekdays are the days, such as "friday", on which "weekly" rules repeat.
+	// Default is the weekday of Start.
+	Weekdays []string `bson:"weekdays,omitempty" json:"weekdays,omitempty"`
+	// Cron is the expression of format "minute hour day-of-month month day-of-week"
+	// for "cron" rules. Fields support "*", values, ranges ("1-5"), steps ("*/2")
+	// and lists ("1,3,5"). Day-of-week is 0 (Sunday) to 6, or 7 for Sunday.
+	Cron string `bson:"cron,omitempty" json:"cron,omitempty"`
+	// Start is the start of first occurrence. "cron" rules have the first
+	// occurrence at or after Start.
+	Start string `bson:"start,omitempty" json:"start,omitempty"`
+	// Duration is the length of each occurrence, in seconds.
+	Duration int64 `bson:"duration,omitempty" json:"duration,omitempty"`
+	// Until is the latest start of an occurrence (inclusive).
+	Until string `bson:"until,omitempty" json:"until,omitempty"`
+	// Count is the maximum number of occurrences.
+	Count int `bson:"count,omitempty" json:"count,omitempty"`
+}
+
+// occurrence is a single occurrence of a RecurrenceRule.
+type occurrence struct {
+	// Key is the local start of occurrence ("2006-01-02T15:04:05") as defined
+	// by the rule, which identifies the occurrence.
+	Key string
+	// Start is the local start of occurrence, as wall-clock time in UTC.
+	Start time.Time
+}
+
+// recurrenceSchedule is the parsed RecurrenceRule.
+type recurrenceSchedule struct {
+	rule     *RecurrenceRule
+	start    time.Time
+	until    time.Time
+	weekdays [7]bool
+	cron     *cronSchedule
+	// firstDay and firstWeek (starting on Sunday) contain Start, and are
+	// the base of Interval.
+	firstDay  time.Time
+	firstWeek time.Time
+}
+
+// schedule validates and parses the RecurrenceRule.
+func (r *RecurrenceRule) schedule() (*recurrenceSchedule, error) {
+	if r.Start == "" {
+		return nil, errors.New("missing Start")
+	}
+	start, err := time.Parse(localTimeLayout, r.Start)
+	if err != nil {
+		return nil, errors.Errorf(
+			"invalid Start: %s, must be of format %s", r.Start, localTimeLayout,
+		)
+	}
+	if r.Duration <= 0 {
+		return nil, errors.New("Duration must be greater than 0")
+	}
+	if r.Until == "" && r.Count == 0 {
+		return nil, errors.New("either Until or Count is required")
+	}
+	if r.Count < 0 {
+		return nil, errors.New("Count cannot be negative")
+	}
+	if r.Interval < 0 || r.Interval > maxRecurrenceInterval {
+		return nil, errors.Errorf(
+			"Interval must be in range [1, %d]", maxRecurrenceInterval,
+		)
+	}
+
+	firstDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	s := &recurrenceSchedule{
+		rule:      r,
+		start:     start,
+		firstDay:  firstDay,
+		firstWeek: firstDay.AddDate(0, 0, -int(firstDay.Weekday())),
+	}
+	if r.Until != "" {
+		s.until, err = time.Parse(localTimeLayout, r.Until)
+		if err != nil {
+			return nil, errors.Errorf(
+				"invalid Until: %s, must be of format %s", r.Until, localTimeLayout,
+			)
+		}
+		if s.until.Before(start) {
+			return nil, errors.New("Until cannot be before Start")
+		}
+	}
+
+	switch r.Frequency {
+	case FrequencyDaily:
+	case FrequencyWeekly:
+		if len(r.Weekdays) == 0 {
+			s.weekdays[start.Weekday()] = true
+		}
+		for _, day := range r.Weekdays {
+			weekday, err := parseWeekday(day)
+			if err != nil {
+				return nil, err
+			}
+			s.weekdays[weekday] = true
+		}
+	case FrequencyCron:
+		s.cron, err = parseCron(r.Cron)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("invalid Frequency: %s", r.Frequency)
+	}
+	return s, nil
+}
+
+func parseWeekday(day string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(day, weekday.String()) {
+			return weekday, nil
+		}
+	}
+	return 0, errors.Errorf("invalid Weekday: %s", day)
+}
+
+// occurrences returns the occurrences of the rule starting at or before
+// the wall-clock time "through", in order.
+func (s *recurrenceSchedule) occurrences(through time.Time) []occurrence {
+	return s.occurrencesAfter(time.Time{}, 0, through, 0)
+}
+
+// occurrencesAfter returns the occurrences of the rule starting after the
+// wall-clock time "after" (if set), and at or before "through", in order.
+// The days before "after" are not enumerated, so "count" is the number of
+// occurrences up to "after", for limiting the occurrences to Count.
+// At most "limit" occurrences are returned, unless limit is 0.
+func (s *recurrenceSchedule) occurrencesAfter(
+	after time.Time,
+	count int,
+	through time.Time,
+	limit int,
+) []occurrence {
+	occurrences := make([]occurrence, 0)
+	if !s.until.IsZero() && s.until.Before(through) {
+		through = s.until
+	}
+	if s.rule.Count > 0 && count >= s.rule.Count {
+		return occurrences
+	}
+
+	firstDay := s.firstDay
+	if after.After(firstDay) {
+		firstDay = time.Date(
+			after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC,
+		)
+	}
+	for day := firstDay; !day.After(through); day = day.AddDate(0, 0, 1) {
+		for _, start := range s.times(day) {
+			if start.Before(s.start) || !start.After(after) {
+				continue
+			}
+			if start.After(through) {
+				return occurrences
+			}
+			occurrences = append(occurrences, occurrence{
+				Key:   start.Format(localTimeLayout),
+				Start: start,
+			})
+			if s.rule.Count > 0 && count+len(occurrences) == s.rule.Count {
+				return occurrences
+			}
+			if limit > 0 && len(occurrences) == limit {
+				return occurrences
+			}
+		}
+	}
+	return occurrences
+}
+
+// times returns the wall-clock times on the day matching the rule, ignoring
+// Start, Until and Count.
+func (s *recurrenceSchedule) times(day time.Time) []time.Time {
+	interval := s.rule.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	switch s.rule.Frequency {
+	case FrequencyDaily:
+		if daysBetween(s.firstDay, day)%interval == 0 {
+			return []time.Time{withClock(day, s.start)}
+		}
+	case FrequencyWeekly:
+		week := daysBetween(s.firstWeek, day) / 7
+		if week%interval == 0 && s.weekdays[day.Weekday()] {
+			return []time.Time{withClock(day, s.start)}
+		}
+	case FrequencyCron:
+		return s.cron.times(day)
+	}
+	return nil
+}
+
+// findOccurrence returns the occurrence of the rule with provided Key.
+func (s *recurrenceSchedule) findOccurrence(key string) (occurrence, error) {
+	return s.findOccurrenceAfter(key, time.Time{}, 0)
+}
+
+// findOccurrenceAfter returns the occurrence of the rule with provided Key,
+// if it starts after the wall-clock time "after" (if set). "count" is the
+// number of occurrences up to "after", so only the occurrences since "after"
+// are enumerated to check the Count of rule.
+func (s *recurrenceSchedule) findOccurrenceAfter(
+	key string,
+	after time.Time,
+	count int,
+) (occurrence, error) {
+	wall, err := time.Parse(localTimeLayout, key)
+	if err == nil && wall.After(after) && s.isOccurrence(wall) {
+		if s.rule.Count == 0 {
+			return occurrence{Key: key, Start: wall}, nil
+		}
+		occurrences := s.occurrencesAfter(after, count, wall, 0)
+		if len(occurrences) > 0 && occurrences[len(occurrences)-1].Key == key {
+			return occurrences[len(occurrences)-1], nil
+		}
+	}
+	return occurrence{}, errors.Errorf("%s is not an occurrence of RecurringSale", key)
+}
+
+// isOccurrence checks if the wall-clock time matches the rule, within
+// Start and Until.
+func (s *recurrenceSchedule) isOccurrence(wall time.Time) bool {
+	if wall.Before(s.start) || (!s.until.IsZero() && wall.After(s.until)) {
+		return false
+	}
+	day := time.Date(wall.Year(), wall.Month(), wall.Day(), 0, 0, 0, 0, time.UTC)
+	for _, t := range s.times(day) {
+		if t.Equal(wall) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComplete checks if the rule has no occurrences after the wall-clock time,
+// given the number of occurrences through it.
+func (s *recurrenceSchedule) isComplete(through time.Time, count int) bool {
+	if !s.until.IsZero() && !s.until.After(through) {
+		return true
+	}
+	return s.rule.Count > 0 && count >= s.rule.Count
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// withClock returns the day with the clock-time of provided time.
+func withClock(day time.Time, clock time.Time) time.Time {
+	return time.Date(
+		day.Year(), day.Month(), day.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), 0,
+		time.UTC,
+	)
+}
+
+// wallClock returns the wall-clock time of t as a time in UTC,
+// for comparing with the occurrences of a rule.
+func wallClock(t time.Time) time.Time {
+	return time.Date(
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC,
+	)
+}
+
+// resolveOccurrenceTime returns the time at which the clocks in Location show
+// the wall-clock time. Unlike resolveLocalTime, wall-clock times skipped by
+// DST-transitions are moved forward by an hour, so the occurrence still happens.
+func resolveOccurrenceTime(wall time.Time, loc *time.Location) time.Time {
+	t, err := resolveLocalTime(wall, loc)
+	if err != nil {
+		t, err = resolveLocalTime(wall.Add(time.Hour), loc)
+	}
+	if err != nil {
+		// Not possible for the DST-transitions in use
+		return time.Date(
+			wall.Year(), wall.Month(), wall.Day(),
+			wall.Hour(), wall.Minute(), wall.Second(), 0,
+			loc,
+		)
+	}
+	return t
+}
+
+// cronSchedule is a parsed Cron-expression.
+type cronSchedule struct {
+	minutes     []int
+	hours       []int
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	weekdays    map[int]bool
+	// The day-restrictions as in Vixie-cron: if day-of-month or day-of-week
+	// starts with "*", days must match both. Otherwise days matching either
+	// are included.
+	matchBothDays bool
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf(
+			"invalid Cron: %s, must have 5 fields: minute hour day-of-month month "+
+				"day-of-week",
+			expr,
+		)
+	}
+
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			err = errors.Wrapf(err, "invalid Cron: %s", expr)
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// Sunday is either 0 or 7
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		minutes:     sortedKeys(sets[0]),
+		hours:       sortedKeys(sets[1]),
+		daysOfMonth: sets[2],
+		months:      sets[3],
+		weekdays:    sets[4],
+		matchBothDays: strings.HasPrefix(fields[2], "*") ||
+			strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField returns the values matching a Cron-field, in range [min, max].
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step in field: %s", field)
+			}
+			part = part[:i]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			low, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.Errorf("invalid value in field: %s", field)
+			}
+			high = low
+			if len(bounds) == 2 {
+				high, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, errors.Errorf("invalid range in field: %s", field)
+				}
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, errors.Errorf(
+				"values must be in range [%d, %d] in field: %s", min, max, field,
+			)
+		}
+		for value := low; value <= high; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// times returns the wall-clock times on the day matching the Cron-expression.
+func (c *cronSchedule) times(day time.Time) []time.Time {
+	if !c.months[int(day.Month())] {
+		return nil
+	}
+	dayOfMonth := c.daysOfMonth[day.Day()]
+	weekday := c.weekdays[int(day.Weekday())]
+	isMatch := dayOfMonth || weekday
+	if c.matchBothDays {
+		isMatch = dayOfMonth && weekday
+	}
+	if !isMatch {
+		return nil
+	}
+
+	times := make([]time.Time, 0, len(c.hours)*len(c.minutes))
+	for _, hour := range c.hours {
+		for _, minute := range c.minutes {
+			times = append(times, time.Date(
+				day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.UTC,
+			))
+		}
+	}
+	return times
+}
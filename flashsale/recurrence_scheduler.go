@@ -0,0 +1,196 @@
+package flashsale
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// RecurrenceConfig configures creating the occurrences of RecurringSales.
+type RecurrenceConfig struct {
+	Interval time.Duration
+	// LookaheadDays is how many days ahead of their start the occurrences
+	// are created as FlashSales. Days are counted on the local calendar of
+	// each RecurringSale.
+	LookaheadDays int
+}
+
+// RecurrenceScheduler periodically creates FlashSales for the occurrences of
+// RecurringSales starting within the configured number of days.
+type RecurrenceScheduler struct {
+	execContext   *ExecContext
+	aggCollection *mongo.Collection
+	recCollection *mongo.Collection
+	config        *RecurrenceConfig
+}
+
+// NewRecurrenceScheduler creates a new RecurrenceScheduler. The occurrences
+// are created in the provided ExecContext.
+func NewRecurrenceScheduler(
+	c *ExecContext,
+	aggCollection *mongo.Collection,
+	config *RecurrenceConfig,
+) (*RecurrenceScheduler, error) {
+	if c == nil {
+		return nil, errors.New("ExecContext cannot be nil")
+	}
+	if aggCollection == nil {
+		return nil, errors.New("aggregate-collection cannot be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.LookaheadDays <= 0 {
+		return nil, errors.New("LookaheadDays must be greater than 0")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("Interval must be greater than 0")
+	}
+	recColl, err := recurrenceCollection(aggCollection, c.cfg.Mongo.RecurrenceCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Error getting recurrence-collection")
+		return nil, err
+	}
+
+	return &RecurrenceScheduler{
+		execContext:   c,
+		aggCollection: aggCollection,
+		recCollection: recColl,
+		config:        config,
+	}, nil
+}
+
+// Run creates the upcoming occurrences every Interval until the context is closed.
+func (s *RecurrenceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Check(time.Now())
+
+		select {
+		case <-ctx.Done():
+			log.Println("RecurrenceScheduler: context closed")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check creates FlashSales for the occurrences of RecurringSales starting within
+// LookaheadDays of provided time. Any creation-errors are logged.
+func (s *RecurrenceScheduler) Check(now time.Time) {
+	findResults, err := s.recCollection.Find(map[string]interface{}{
+		"complete": map[string]interface{}{
+			"$ne": true,
+		},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "RecurrenceScheduler: Error finding RecurringSales")
+		log.Println(err)
+		return
+	}
+
+	for _, r := range findResults {
+		recurringSale, assertOK := r.(*RecurringSale)
+		if !assertOK {
+			log.Println(
+				"RecurrenceScheduler: Error asserting find-result to RecurringSale",
+			)
+			continue
+		}
+		err := s.createOccurrences(recurringSale, now)
+		if err != nil {
+			err = errors.Wrapf(
+				err,
+				"RecurrenceScheduler: Error creating occurrences of RecurringSale %s",
+				recurringSale.RecurrenceID,
+			)
+			log.Println(err)
+		}
+	}
+}
+
+// createOccurrences creates FlashSales for the occurrences after LastOccurrence
+// which start within LookaheadDays, and records the progress in RecurringSale.
+// Skipped occurrences, and the occurrences which already ended (such as while
+// the service was stopped) are not created.
+func (s *RecurrenceScheduler) createOccurrences(r *RecurringSale, now time.Time) error {
+	loc, err := loadTimeZone(r.TimeZone)
+	if err != nil {
+		return err
+	}
+	schedule, err := r.Rule.schedule()
+	if err != nil {
+		err = errors.Wrap(err, "invalid RecurrenceRule")
+		return err
+	}
+
+	after, err := r.lastOccurrenceTime()
+	if err != nil {
+		return err
+	}
+
+	// Days are added on the local calendar, so DST-transitions are accounted for.
+	// Only the days since LastOccurrence are enumerated, with the count of
+	// occurrences up to it.
+	through := wallClock(now.In(loc).AddDate(0, 0, s.config.LookaheadDays))
+	occurrences := schedule.occurrencesAfter(after, r.OccurrenceCount, through, 0)
+	lastOccurrence := r.LastOccurrence
+	for _, o := range occurrences {
+		lastOccurrence = o.Key
+		if r.isSkipped(o.Key) {
+			continue
+		}
+
+		flashSale, err := r.occurrenceSale(o, loc, now)
+		if err != nil {
+			return err
+		}
+		if flashSale.EndTime <= now.Unix() {
+			continue
+		}
+		err = s.createSale(flashSale, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	occurrenceCount := r.OccurrenceCount + len(occurrences)
+	isComplete := schedule.isComplete(through, occurrenceCount)
+	if lastOccurrence == r.LastOccurrence && !isComplete {
+		return nil
+	}
+	_, err = s.recCollection.UpdateMany(
+		map[string]interface{}{
+			"recurrenceID": r.RecurrenceID.String(),
+		},
+		map[string]interface{}{
+			"lastOccurrence":  lastOccurrence,
+			"occurrenceCount": occurrenceCount,
+			"complete":        isComplete,
+		},
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error updating RecurringSale")
+		return err
+	}
+	return nil
+}
+
+// createSale publishes a "flashSaleCreated" Event for the FlashSale, so it is
+// created like other FlashSales when the Event is polled.
+// FlashSales which already exist (such as from a previous run interrupted before
+// recording its progress) are not published again.
+func (s *RecurrenceScheduler) createSale(flashSale *FlashSale, now time.Time) error {
+	_, err := s.aggCollection.FindOne(map[string]interface{}{
+		"flashSaleID": flashSale.FlashSaleID.String(),
+	})
+	if err == nil {
+		return nil
+	}
+	return s.execContext.publishCreatedSale(s.aggCollection, flashSale, now)
+}
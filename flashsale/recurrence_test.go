@@ -0,0 +1,395 @@
+package flashsale
+
+import (
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecurringSale", func() {
+	var (
+		toronto       *time.Location
+		recurringSale *RecurringSale
+	)
+
+	// keys returns the occurrence-Keys of the rule until the local time.
+	keys := func(rule *RecurrenceRule, through string) []string {
+		schedule, err := rule.schedule()
+		Expect(err).ToNot(HaveOccurred())
+		throughWall, err := time.Parse(localTimeLayout, through)
+		Expect(err).ToNot(HaveOccurred())
+
+		keys := []string{}
+		for _, o := range schedule.occurrences(throughWall) {
+			keys = append(keys, o.Key)
+		}
+		return keys
+	}
+
+	BeforeEach(func() {
+		var err error
+		toronto, err = loadTimeZone("America/Toronto")
+		Expect(err).ToNot(HaveOccurred())
+
+		recurrenceID, err := uuuid.FromString("9b0e4c52-6f0a-4d1e-8a8c-3f2b1d5e7a90")
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.FromString("2c6f1b8e-4a3d-4e5f-9b7a-1d0c8e6f4a21")
+		Expect(err).ToNot(HaveOccurred())
+
+		// Friday afternoon produce blowout
+		recurringSale = &RecurringSale{
+			RecurrenceID: recurrenceID,
+			Items: []SoldItem{
+				SoldItem{
+					ItemID:   itemID,
+					Weight:   50,
+					Price:    1.5,
+					Discount: 40,
+				},
+			},
+			TimeZone: "America/Toronto",
+			Rule: RecurrenceRule{
+				Frequency: FrequencyWeekly,
+				Start:     "2018-10-26T15:00:00",
+				Duration:  3 * 3600,
+				Count:     10,
+			},
+			Timestamp: time.Now().Unix(),
+		}
+	})
+
+	Describe("RecurrenceRule", func() {
+		It("should repeat weekly on weekday of Start by default", func() {
+			Expect(keys(&recurringSale.Rule, "2018-11-10T00:00:00")).To(Equal([]string{
+				"2018-10-26T15:00:00",
+				"2018-11-02T15:00:00",
+				"2018-11-09T15:00:00",
+			}))
+		})
+
+		It("should repeat weekly on Weekdays of every Interval weeks", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyWeekly,
+				Interval:  2,
+				Weekdays:  []string{"Tuesday", "friday"},
+				Start:     "2018-10-26T15:00:00",
+				Duration:  3600,
+				Count:     3,
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-26T15:00:00",
+				"2018-11-06T15:00:00",
+				"2018-11-09T15:00:00",
+			}))
+		})
+
+		It("should repeat daily every Interval days until Until", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyDaily,
+				Interval:  2,
+				Start:     "2018-10-26T09:00:00",
+				Duration:  3600,
+				Until:     "2018-10-30T09:00:00",
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-26T09:00:00",
+				"2018-10-28T09:00:00",
+				"2018-10-30T09:00:00",
+			}))
+		})
+
+		It("should repeat at times matching Cron-expression after Start", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyCron,
+				Cron:      "0 9,17 * * 1-5",
+				Start:     "2018-10-26T12:00:00",
+				Duration:  3600,
+				Count:     3,
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-26T17:00:00",
+				"2018-10-29T09:00:00",
+				"2018-10-29T17:00:00",
+			}))
+		})
+
+		It("should match either day-of-month or day-of-week if both are set", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyCron,
+				Cron:      "30 12 1 * 0",
+				Start:     "2018-10-26T00:00:00",
+				Duration:  3600,
+				Count:     3,
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-28T12:30:00",
+				"2018-11-01T12:30:00",
+				"2018-11-04T12:30:00",
+			}))
+		})
+
+		It("should apply steps in day-of-week", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyCron,
+				Cron:      "0 9 * * */2",
+				Start:     "2018-10-26T00:00:00",
+				Duration:  3600,
+				Count:     3,
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-27T09:00:00",
+				"2018-10-28T09:00:00",
+				"2018-10-30T09:00:00",
+			}))
+		})
+
+		It("should apply steps in day-of-month", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyCron,
+				Cron:      "0 9 */2 * *",
+				Start:     "2018-10-26T00:00:00",
+				Duration:  3600,
+				Count:     3,
+			}
+			Expect(keys(rule, "2019-01-01T00:00:00")).To(Equal([]string{
+				"2018-10-27T09:00:00",
+				"2018-10-29T09:00:00",
+				"2018-10-31T09:00:00",
+			}))
+		})
+
+		It("should match both day-of-month and day-of-week if either is starred", func() {
+			rule := &RecurrenceRule{
+				Frequency: FrequencyCron,
+				Cron:      "0 9 */10 * 1",
+				Start:     "2018-09-26T00:00:00",
+				Duration:  3600,
+				Count:     2,
+			}
+			Expect(keys(rule, "2019-02-01T00:00:00")).To(Equal([]string{
+				"2018-10-01T09:00:00",
+				"2018-12-31T09:00:00",
+			}))
+		})
+
+		It("should be complete once all occurrences are through", func() {
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			through := time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)
+			count := len(schedule.occurrences(through))
+			Expect(schedule.isComplete(through, count)).To(BeFalse())
+
+			through = time.Date(2018, 12, 28, 15, 0, 0, 0, time.UTC)
+			count = len(schedule.occurrences(through))
+			Expect(schedule.isComplete(through, count)).To(BeTrue())
+		})
+
+		It("should continue the occurrences after the last occurrence", func() {
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			through := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+			all := schedule.occurrences(through)
+			Expect(all).To(HaveLen(10))
+
+			// Third occurrence is 9 November
+			after := time.Date(2018, 11, 9, 15, 0, 0, 0, time.UTC)
+			occurrences := schedule.occurrencesAfter(after, 3, through, 0)
+			Expect(occurrences).To(Equal(all[3:]))
+
+			occurrences = schedule.occurrencesAfter(time.Time{}, 0, through, 1)
+			Expect(occurrences).To(Equal(all[:1]))
+
+			o, err := schedule.findOccurrenceAfter("2018-12-28T15:00:00", after, 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(o).To(Equal(all[9]))
+			_, err = schedule.findOccurrenceAfter("2019-01-04T15:00:00", after, 3)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return error for invalid rules", func() {
+			invalidRules := []RecurrenceRule{
+				RecurrenceRule{Frequency: "monthly"},
+				RecurrenceRule{Frequency: FrequencyCron, Cron: "* * *"},
+				RecurrenceRule{Frequency: FrequencyCron, Cron: "61 * * * *"},
+				RecurrenceRule{Frequency: FrequencyCron, Cron: "*/0 * * * *"},
+				RecurrenceRule{Frequency: FrequencyCron, Cron: "0 17-9 * * *"},
+				RecurrenceRule{Frequency: FrequencyWeekly, Weekdays: []string{"fri"}},
+			}
+			for _, rule := range invalidRules {
+				rule.Start = "2018-10-26T15:00:00"
+				rule.Duration = 3600
+				rule.Count = 1
+				_, err := rule.schedule()
+				Expect(err).To(HaveOccurred())
+			}
+
+			rule := recurringSale.Rule
+			rule.Count = 0
+			_, err := rule.schedule()
+			Expect(err).To(HaveOccurred())
+
+			rule = recurringSale.Rule
+			rule.Until = "2018-10-01T00:00:00"
+			_, err = rule.schedule()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("occurrenceSale", func() {
+		It("should start occurrences at same local time across DST-transitions", func() {
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			through := time.Date(2018, 11, 10, 0, 0, 0, 0, time.UTC)
+			occurrences := schedule.occurrences(through)
+			Expect(occurrences).To(HaveLen(3))
+
+			// Toronto is on EDT (UTC-4) on 26 October
+			sale, err := recurringSale.occurrenceSale(occurrences[0], toronto, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.StartTime).To(Equal(
+				time.Date(2018, 10, 26, 19, 0, 0, 0, time.UTC).Unix(),
+			))
+			Expect(sale.EndTime).To(Equal(
+				time.Date(2018, 10, 26, 22, 0, 0, 0, time.UTC).Unix(),
+			))
+			Expect(ValidateFlashSale(sale)).To(Succeed())
+
+			// and on EST (UTC-5) on 9 November
+			sale, err = recurringSale.occurrenceSale(occurrences[2], toronto, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.StartTime).To(Equal(
+				time.Date(2018, 11, 9, 20, 0, 0, 0, time.UTC).Unix(),
+			))
+		})
+
+		It("should move occurrences skipped by DST-transition forward", func() {
+			recurringSale.Rule = RecurrenceRule{
+				Frequency: FrequencyDaily,
+				Start:     "2019-03-09T02:30:00",
+				Duration:  3600,
+				Count:     2,
+			}
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			through := time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC)
+			occurrences := schedule.occurrences(through)
+			Expect(occurrences).To(HaveLen(2))
+
+			// Clocks moved from 02:00 to 03:00 on 10 March 2019
+			sale, err := recurringSale.occurrenceSale(occurrences[1], toronto, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.StartTime).To(Equal(
+				time.Date(2019, 3, 10, 7, 30, 0, 0, time.UTC).Unix(),
+			))
+		})
+
+		It("should derive a stable FlashSaleID for each occurrence", func() {
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			o, err := schedule.findOccurrence("2018-11-02T15:00:00")
+			Expect(err).ToNot(HaveOccurred())
+
+			sale, err := recurringSale.occurrenceSale(o, toronto, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.FlashSaleID).To(Equal(
+				occurrenceSaleID(recurringSale.RecurrenceID, "2018-11-02T15:00:00"),
+			))
+			Expect(sale.FlashSaleID).ToNot(Equal(
+				occurrenceSaleID(recurringSale.RecurrenceID, "2018-11-09T15:00:00"),
+			))
+		})
+
+		It("should derive FlashSaleID as version-5 UUID", func() {
+			recurrenceID, err := uuuid.FromString("6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e")
+			Expect(err).ToNot(HaveOccurred())
+
+			flashSaleID := occurrenceSaleID(recurrenceID, "2018-11-02T15:00:00")
+			Expect(flashSaleID.String()).To(Equal("1e49dbf1-39d3-5d4f-8e6a-11da73148008"))
+			sameID := occurrenceSaleID(recurrenceID, "2018-11-02T15:00:00")
+			Expect(sameID).To(Equal(flashSaleID))
+		})
+	})
+
+	Describe("changeRecurringSale", func() {
+		skip := func(r *RecurringSale, req *occurrenceRequest) error {
+			r.Skipped = append(r.Skipped, req.Occurrence)
+			return nil
+		}
+
+		It("should skip an upcoming occurrence", func() {
+			req := &occurrenceRequest{Occurrence: "2018-11-09T15:00:00"}
+			Expect(changeRecurringSale(recurringSale, req, skip)).To(Succeed())
+			Expect(recurringSale.isSkipped("2018-11-09T15:00:00")).To(BeTrue())
+		})
+
+		It("should modify start of an upcoming occurrence", func() {
+			recurringSale.Overrides = []OccurrenceOverride{
+				OccurrenceOverride{
+					Occurrence: "2018-11-09T15:00:00",
+					Start:      "2018-11-10T10:00:00",
+				},
+			}
+			schedule, err := recurringSale.Rule.schedule()
+			Expect(err).ToNot(HaveOccurred())
+			o, err := schedule.findOccurrence("2018-11-09T15:00:00")
+			Expect(err).ToNot(HaveOccurred())
+
+			sale, err := recurringSale.occurrenceSale(o, toronto, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.StartTime).To(Equal(
+				time.Date(2018, 11, 10, 15, 0, 0, 0, time.UTC).Unix(),
+			))
+			Expect(sale.Items).To(Equal(recurringSale.Items))
+		})
+
+		It("should return error for times which are not occurrences", func() {
+			req := &occurrenceRequest{Occurrence: "2018-11-08T15:00:00"}
+			Expect(changeRecurringSale(recurringSale, req, skip)).ToNot(Succeed())
+		})
+
+		It("should return error for occurrences already created as FlashSales", func() {
+			recurringSale.LastOccurrence = "2018-11-09T15:00:00"
+			req := &occurrenceRequest{Occurrence: "2018-11-02T15:00:00"}
+			err := changeRecurringSale(recurringSale, req, skip)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already created"))
+		})
+
+		It("should return error if modified occurrence is invalid", func() {
+			req := &occurrenceRequest{Occurrence: "2018-11-09T15:00:00"}
+			err := changeRecurringSale(
+				recurringSale, req,
+				func(r *RecurringSale, req *occurrenceRequest) error {
+					r.Overrides = append(r.Overrides, OccurrenceOverride{
+						Occurrence: req.Occurrence,
+						Duration:   -3600,
+					})
+					return nil
+				},
+			)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("should validate RecurringSale with its first occurrence", func() {
+		Expect(validateRecurringSale(recurringSale)).To(Succeed())
+
+		recurringSale.Items[0].Weight = 0
+		Expect(validateRecurringSale(recurringSale)).ToNot(Succeed())
+	})
+
+	It("should return error if RecurrenceID is missing", func() {
+		c := NewExecContext(config.Default())
+		doc := recurringSaleOccurrenceSkipped(c, nil, &model.Event{
+			EventAction:   "update",
+			ServiceAction: "recurringSaleOccurrenceSkipped",
+			Data:          []byte(`{"occurrence": "2018-11-09T15:00:00"}`),
+		})
+		Expect(doc.Error).To(ContainSubstring("missing RecurrenceID"))
+		Expect(doc.ErrorCode).To(Equal(int16(InternalError)))
+	})
+})
@@ -0,0 +1,173 @@
+package flashsale
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// Handler handles an Event, and returns the response-Document to be produced, if any.
+type Handler func(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document
+
+// HandlerKey is the EventAction and ServiceAction of Events a Handler is
+// registered for. A blank ServiceAction matches the Events without one.
+type HandlerKey struct {
+	EventAction   string `json:"eventAction"`
+	ServiceAction string `json:"serviceAction"`
+}
+
+// Registry routes Events to the Handlers registered for their
+// EventAction and ServiceAction.
+type Registry struct {
+	handlersLock sync.RWMutex
+	handlers     map[HandlerKey]Handler
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: map[HandlerKey]Handler{},
+	}
+}
+
+// DefaultRegistry creates a Registry with the Handlers of FlashSale Aggregate.
+// The "insert", "update", and "delete" Events without a ServiceAction create,
+// update, and delete FlashSales respectively.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	handlers := []struct {
+		key     HandlerKey
+		handler Handler
+	}{
+		{HandlerKey{"insert", ""}, flashSaleCreated},
+		{HandlerKey{"insert", "flashSaleCreated"}, flashSaleCreated},
+		{HandlerKey{"insert", "flashSaleValidated"}, flashSaleValidated},
+		{HandlerKey{"insert", "flashSaleFromTemplate"}, flashSaleFromTemplate},
+		{HandlerKey{"insert", "flashSaleCloned"}, flashSaleCloned},
+		{HandlerKey{"insert", "templateCreated"}, templateCreated},
+		{HandlerKey{"insert", "recurringSaleCreated"}, recurringSaleCreated},
+
+		{HandlerKey{"update", ""}, (*ExecContext).update},
+		{HandlerKey{"update", "activateFlashSale"}, activateFlashSale},
+		{HandlerKey{"update", "approveFlashSale"}, approveFlashSale},
+		{HandlerKey{"update", "rejectFlashSale"}, rejectFlashSale},
+		{
+			HandlerKey{"update", "recurringSaleOccurrenceSkipped"},
+			recurringSaleOccurrenceSkipped,
+		},
+		{
+			HandlerKey{"update", "recurringSaleOccurrenceModified"},
+			recurringSaleOccurrenceModified,
+		},
+		{HandlerKey{"delete", ""}, (*ExecContext).delete},
+
+		{HandlerKey{"query", "flashSaleByID"}, flashSaleByID},
+		{HandlerKey{"query", "activeFlashSales"}, activeFlashSales},
+		{HandlerKey{"query", "flashSalesByItem"}, flashSalesByItem},
+		{HandlerKey{"query", "flashSaleHistory"}, flashSaleHistory},
+	}
+	for _, h := range handlers {
+		err := r.Register(h.key.EventAction, h.key.ServiceAction, h.handler)
+		if err != nil {
+			// Only possible if the list above has duplicates
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register registers the Handler for Events with the EventAction and
+// ServiceAction. An error is returned if a Handler is already registered.
+func (r *Registry) Register(
+	eventAction string,
+	serviceAction string,
+	handler Handler,
+) error {
+	if eventAction == "" {
+		return errors.New("eventAction cannot be blank")
+	}
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	key := HandlerKey{
+		EventAction:   eventAction,
+		ServiceAction: serviceAction,
+	}
+
+	r.handlersLock.Lock()
+	defer r.handlersLock.Unlock()
+	if r.handlers[key] != nil {
+		return errors.Errorf(
+			"handler already registered for EventAction: %s, ServiceAction: %s",
+			eventAction, serviceAction,
+		)
+	}
+	r.handlers[key] = handler
+	return nil
+}
+
+// Lookup returns the Handler registered for the EventAction and ServiceAction.
+func (r *Registry) Lookup(eventAction string, serviceAction string) (Handler, bool) {
+	r.handlersLock.RLock()
+	defer r.handlersLock.RUnlock()
+	handler, ok := r.handlers[HandlerKey{
+		EventAction:   eventAction,
+		ServiceAction: serviceAction,
+	}]
+	return handler, ok
+}
+
+// List returns the HandlerKeys of registered Handlers, sorted by
+// EventAction and ServiceAction.
+func (r *Registry) List() []HandlerKey {
+	r.handlersLock.RLock()
+	keys := make([]HandlerKey, 0, len(r.handlers))
+	for key := range r.handlers {
+		keys = append(keys, key)
+	}
+	r.handlersLock.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].EventAction != keys[j].EventAction {
+			return keys[i].EventAction < keys[j].EventAction
+		}
+		return keys[i].ServiceAction < keys[j].ServiceAction
+	})
+	return keys
+}
+
+// dispatch runs the Handler registered for the Event. An UnsupportedActionError
+// Document is returned if no Handler is registered.
+func (r *Registry) dispatch(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	handler, ok := r.Lookup(event.EventAction, event.ServiceAction)
+	if !ok {
+		err := errors.Errorf(
+			"unsupported EventAction: %s, ServiceAction: %s",
+			event.EventAction, event.ServiceAction,
+		)
+		err = errors.Wrap(err, "Registry")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     UnsupportedActionError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return handler(c, collection, event)
+}
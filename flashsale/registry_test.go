@@ -0,0 +1,111 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		r       *Registry
+		handled *model.Event
+		handler Handler
+	)
+
+	BeforeEach(func() {
+		r = NewRegistry()
+		handled = nil
+		handler = func(
+			c *ExecContext,
+			collection *mongo.Collection,
+			event *model.Event,
+		) *model.Document {
+			handled = event
+			return &model.Document{
+				UUID: event.UUID,
+			}
+		}
+	})
+
+	It("should route Events to Handler registered for their actions", func() {
+		err := r.Register("insert", "flashSaleCloned", handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		uuid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		event := &model.Event{
+			EventAction:   "insert",
+			ServiceAction: "flashSaleCloned",
+			UUID:          uuid,
+		}
+		doc := r.dispatch(nil, nil, event)
+		Expect(handled).To(Equal(event))
+		Expect(doc.UUID).To(Equal(uuid))
+	})
+
+	It("should return error if Handler is already registered", func() {
+		err := r.Register("update", "", handler)
+		Expect(err).ToNot(HaveOccurred())
+		err = r.Register("update", "", handler)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error for blank EventAction or nil Handler", func() {
+		Expect(r.Register("", "flashSaleCloned", handler)).ToNot(Succeed())
+		Expect(r.Register("insert", "flashSaleCloned", nil)).ToNot(Succeed())
+	})
+
+	It("should return unsupported-action error for unregistered actions", func() {
+		err := r.Register("insert", "", handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		cid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		event := &model.Event{
+			AggregateID:   AggregateID,
+			CorrelationID: cid,
+			EventAction:   "insert",
+			ServiceAction: "flashSaleRenamed",
+		}
+		doc := r.dispatch(nil, nil, event)
+		Expect(handled).To(BeNil())
+		Expect(doc.Error).To(ContainSubstring("flashSaleRenamed"))
+		Expect(doc.ErrorCode).To(Equal(int16(UnsupportedActionError)))
+		Expect(doc.CorrelationID).To(Equal(cid))
+		Expect(doc.ServiceAction).To(Equal("flashSaleRenamed"))
+	})
+
+	It("should list registered actions in order", func() {
+		Expect(r.Register("query", "flashSaleByID", handler)).To(Succeed())
+		Expect(r.Register("insert", "templateCreated", handler)).To(Succeed())
+		Expect(r.Register("insert", "", handler)).To(Succeed())
+
+		Expect(r.List()).To(Equal([]HandlerKey{
+			HandlerKey{"insert", ""},
+			HandlerKey{"insert", "templateCreated"},
+			HandlerKey{"query", "flashSaleByID"},
+		}))
+	})
+
+	It("should register FlashSale Handlers in DefaultRegistry", func() {
+		keys := DefaultRegistry().List()
+		Expect(keys).To(ContainElement(HandlerKey{"insert", ""}))
+		Expect(keys).To(ContainElement(HandlerKey{"insert", "flashSaleValidated"}))
+		Expect(keys).To(ContainElement(HandlerKey{"update", ""}))
+		Expect(keys).To(ContainElement(HandlerKey{"delete", ""}))
+		Expect(keys).To(ContainElement(HandlerKey{"query", "flashSaleHistory"}))
+	})
+
+	It("should respond with unsupported-action error through ExecContext", func() {
+		c := NewExecContext(config.Default())
+		doc := c.Handle(nil, &model.Event{
+			EventAction:   "insert",
+			ServiceAction: "flashSaleRenamed",
+		})
+		Expect(doc.ErrorCode).To(Equal(int16(UnsupportedActionError)))
+	})
+})
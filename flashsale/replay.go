@@ -0,0 +1,37 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// Apply runs the Event-handler for a previously processed Event, and returns the
+// error from the resulting Document, if any. Only the Events changing the
+// aggregate-collection are applied. Templates and RecurringSales are not part of
+// the aggregate-collection, so the Events changing those are skipped.
+func (c *ExecContext) Apply(collection *mongo.Collection, event *model.Event) error {
+	switch event.EventAction {
+	case "insert", "update", "delete":
+	default:
+		return nil
+	}
+	switch event.ServiceAction {
+	case "templateCreated",
+		"recurringSaleCreated",
+		"recurringSaleOccurrenceSkipped",
+		"recurringSaleOccurrenceModified":
+		return nil
+	}
+
+	doc := c.registry.dispatch(c, collection, event)
+
+	if doc == nil {
+		return nil
+	}
+	c.respond(doc)
+	if doc.Error != "" {
+		return errors.Errorf("ErrorCode %d: %s", doc.ErrorCode, doc.Error)
+	}
+	return nil
+}
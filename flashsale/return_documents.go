@@ -0,0 +1,32 @@
+package flashsale
+
+import (
+	"sort"
+
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+)
+
+// returnDocumentsKey is the Event-data key for including the affected
+// FlashSales in the Update and Delete results.
+const returnDocumentsKey = "returnDocuments"
+
+// returnedSales returns the FlashSales, in order of their ObjectIDs, to be
+// included in results. The returned bool is true if the FlashSales
+// exceeded the maximum and were truncated.
+func returnedSales(
+	sales map[objectid.ObjectID]*FlashSale,
+	max int,
+) ([]*FlashSale, bool) {
+	result := make([]*FlashSale, 0)
+	for _, sale := range sales {
+		result = append(result, sale)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID.Hex() < result[j].ID.Hex()
+	})
+
+	if len(result) > max {
+		return result[:max], true
+	}
+	return result, false
+}
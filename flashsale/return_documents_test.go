@@ -0,0 +1,35 @@
+package flashsale
+
+import (
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("returnedSales", func() {
+	var sales map[objectid.ObjectID]*FlashSale
+
+	BeforeEach(func() {
+		sales = map[objectid.ObjectID]*FlashSale{}
+		for i := 0; i < 3; i++ {
+			id := objectid.New()
+			sales[id] = &FlashSale{
+				ID: id,
+			}
+		}
+	})
+
+	It("should return FlashSales in order of ObjectIDs", func() {
+		result, truncated := returnedSales(sales, 100)
+		Expect(truncated).To(BeFalse())
+		Expect(result).To(HaveLen(3))
+		Expect(result[0].ID.Hex() < result[1].ID.Hex()).To(BeTrue())
+		Expect(result[1].ID.Hex() < result[2].ID.Hex()).To(BeTrue())
+	})
+
+	It("should truncate FlashSales exceeding the maximum", func() {
+		result, truncated := returnedSales(sales, 2)
+		Expect(truncated).To(BeTrue())
+		Expect(result).To(HaveLen(2))
+	})
+})
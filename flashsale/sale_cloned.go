@@ -0,0 +1,118 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// cloneSaleRequest is the Event-data for cloning a FlashSale.
+// The optional time-window overrides that of the cloned FlashSale.
+type cloneSaleRequest struct {
+	FlashSaleID string `json:"flashSaleID"`
+	StartTime   int64  `json:"startTime,omitempty"`
+	EndTime     int64  `json:"endTime,omitempty"`
+}
+
+func flashSaleCloned(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	req := &cloneSaleRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Clone: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if req.FlashSaleID == "" {
+		err = errors.New("missing FlashSaleID")
+		err = errors.Wrap(err, "Clone")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	findResult, err := collection.FindOne(map[string]interface{}{
+		"flashSaleID": req.FlashSaleID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Clone: Error finding FlashSale to clone")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	source, assertOK := findResult.(*FlashSale)
+	if !assertOK {
+		err = errors.New("error asserting find-result to FlashSale")
+		err = errors.Wrap(err, "Clone")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	clone := cloneSale(source, eventSaleID(event.UUID), req, time.Now())
+	return createDerivedSale(c, collection, event, clone)
+}
+
+// cloneSale creates a draft copy of source with the provided FlashSaleID and
+// the time-window overrides from request. The approval of source is not carried
+// over, so the clone is reviewed on its own.
+func cloneSale(
+	source *FlashSale,
+	flashSaleID uuuid.UUID,
+	req *cloneSaleRequest,
+	now time.Time,
+) *FlashSale {
+	clone := *source
+	clone.ID = objectid.NilObjectID
+	clone.FlashSaleID = flashSaleID
+	clone.Status = StatusDraft
+	clone.Timestamp = now.Unix()
+	clone.ReviewedBy = uuuid.UUID{}
+	clone.ReviewedAt = 0
+	clone.SubmittedBy = uuuid.UUID{}
+	if req.StartTime != 0 {
+		clone.StartTime = req.StartTime
+	}
+	if req.EndTime != 0 {
+		clone.EndTime = req.EndTime
+	}
+	return &clone
+}
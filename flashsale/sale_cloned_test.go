@@ -0,0 +1,95 @@
+package flashsale
+
+import (
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FlashSaleCloned", func() {
+	var source *FlashSale
+
+	BeforeEach(func() {
+		flashSaleID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		source = &FlashSale{
+			ID:          objectid.New(),
+			FlashSaleID: flashSaleID,
+			Items: []SoldItem{
+				SoldItem{
+					ItemID: itemID,
+					Weight: 12.24,
+					Lot:    "test-lot",
+				},
+			},
+			Status:    StatusActive,
+			StartTime: 1541250000,
+			EndTime:   1541253600,
+			Timestamp: 1541240000,
+		}
+	})
+
+	It("should create a draft with provided FlashSaleID", func() {
+		flashSaleID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		now := time.Now()
+		clone := cloneSale(source, flashSaleID, &cloneSaleRequest{
+			FlashSaleID: source.FlashSaleID.String(),
+		}, now)
+
+		Expect(clone.ID).To(Equal(objectid.NilObjectID))
+		Expect(clone.FlashSaleID).To(Equal(flashSaleID))
+		Expect(clone.Status).To(Equal(StatusDraft))
+		Expect(clone.Timestamp).To(Equal(now.Unix()))
+		Expect(clone.Items).To(Equal(source.Items))
+		Expect(clone.StartTime).To(Equal(source.StartTime))
+		Expect(clone.EndTime).To(Equal(source.EndTime))
+	})
+
+	It("should apply time-window overrides", func() {
+		req := &cloneSaleRequest{
+			FlashSaleID: source.FlashSaleID.String(),
+			StartTime:   1541340000,
+			EndTime:     1541343600,
+		}
+		clone := cloneSale(source, eventSaleID(source.FlashSaleID), req, time.Now())
+		Expect(clone.StartTime).To(Equal(req.StartTime))
+		Expect(clone.EndTime).To(Equal(req.EndTime))
+	})
+
+	It("should not copy the approval of cloned FlashSale", func() {
+		reviewerID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		submitterID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		source.Status = StatusApproved
+		source.ReviewedBy = reviewerID
+		source.ReviewedAt = 1541245000
+		source.SubmittedBy = submitterID
+
+		clone := cloneSale(source, eventSaleID(source.FlashSaleID), &cloneSaleRequest{
+			FlashSaleID: source.FlashSaleID.String(),
+		}, time.Now())
+		Expect(clone.ReviewedBy).To(Equal(uuuid.UUID{}))
+		Expect(clone.ReviewedAt).To(BeZero())
+		Expect(clone.SubmittedBy).To(Equal(uuuid.UUID{}))
+		Expect(source.ReviewedBy).To(Equal(reviewerID))
+		Expect(source.SubmittedBy).To(Equal(submitterID))
+	})
+
+	It("should not modify the cloned FlashSale", func() {
+		flashSaleID := source.FlashSaleID
+		cloneSale(source, eventSaleID(source.FlashSaleID), &cloneSaleRequest{
+			StartTime: 1541340000,
+		}, time.Now())
+		Expect(source.FlashSaleID).To(Equal(flashSaleID))
+		Expect(source.Status).To(Equal(StatusActive))
+		Expect(source.StartTime).To(Equal(int64(1541250000)))
+	})
+})
@@ -3,18 +3,20 @@ package flashsale
 import (
 	"encoding/json"
 	"log"
-	"os"
 	"time"
 
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventstore-models/model"
-	"github.com/TerrexTech/go-kafkautils/kafka"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
 	"github.com/pkg/errors"
 )
 
-func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.Document {
+func flashSaleCreated(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
 	flashSale := &FlashSale{}
 	err := json.Unmarshal(event.Data, flashSale)
 	if err != nil {
@@ -31,22 +33,11 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 		}
 	}
 
-	if flashSale.FlashSaleID == (uuuid.UUID{}) {
-		err = errors.New("missing FlashSaleID")
-		err = errors.Wrap(err, "Insert")
-		log.Println(err)
-		return &model.Document{
-			AggregateID:   event.AggregateID,
-			CorrelationID: event.CorrelationID,
-			Error:         err.Error(),
-			ErrorCode:     InternalError,
-			EventAction:   event.EventAction,
-			ServiceAction: event.ServiceAction,
-			UUID:          event.UUID,
-		}
+	if flashSale.Status == "" {
+		flashSale.Status = StatusActive
 	}
-	if len(flashSale.Items) == 0 {
-		err = errors.New("missing FlashSaleItems")
+	err = ValidateFlashSale(flashSale)
+	if err != nil {
 		err = errors.Wrap(err, "Insert")
 		log.Println(err)
 		return &model.Document{
@@ -59,9 +50,10 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 			UUID:          event.UUID,
 		}
 	}
-	if flashSale.Timestamp == 0 {
-		err = errors.New("missing Timestamp")
-		err = errors.Wrap(err, "Insert")
+
+	marshalItems, err := json.Marshal(flashSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling FlashSaleItems")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -74,9 +66,9 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 		}
 	}
 
-	marshalItems, err := json.Marshal(flashSale)
+	uuid, err := uuuid.NewV4()
 	if err != nil {
-		err = errors.Wrap(err, "Error marshalling FlashSaleItems")
+		err = errors.Wrap(err, "Error generating UUID")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -89,8 +81,9 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 		}
 	}
 
-	uuid, err := uuuid.NewV4()
-	if err != nil {
+	cid := event.CorrelationID
+	if cid == (uuuid.UUID{}) {
+		cid, err = uuuid.NewV4()
 		err = errors.Wrap(err, "Error generating UUID")
 		log.Println(err)
 		return &model.Document{
@@ -104,27 +97,107 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 		}
 	}
 
-	cid := event.CorrelationID
-	if cid == (uuuid.UUID{}) {
-		cid, err = uuuid.NewV4()
-		err = errors.Wrap(err, "Error generating UUID")
+	isHeld := flashSale.Status == StatusActive &&
+		requiresApproval(flashSale, &c.cfg.Approval)
+	// Drafts are stored directly, and their items are validated with the
+	// inventory once they are activated by "activateFlashSale" Event
+	isDraft := flashSale.Status == StatusDraft
+	var auditColl *mongo.Collection
+	if isHeld || isDraft {
+		auditColl, err = auditCollection(collection, c.cfg.Mongo.AuditCollection)
+		if err != nil {
+			err = errors.Wrap(err, "Insert: Error getting audit-collection")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     DatabaseError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	// The FlashSale is checked in the same transaction as publishing the Event
+	// or storing the FlashSale, so no conflicting FlashSale is inserted
+	// in between
+	var errorCode int16
+	var conflictIDs []string
+	var storedSale *FlashSale
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		errorCode = DatabaseError
+		findResults, err := collection.Find(map[string]interface{}{
+			"flashSaleID": flashSale.FlashSaleID.String(),
+		}, tx.find()...)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding FlashSale")
+			return err
+		}
+		if len(findResults) > 0 {
+			errorCode = InternalError
+			return errors.New("the flashSale is already inserted")
+		}
+
+		conflictIDs, err = findConflictingSales(collection, flashSale, tx.find()...)
+		if err != nil {
+			return err
+		}
+		if len(conflictIDs) > 0 {
+			errorCode = SaleConflictError
+			return errors.New(
+				"the flashSale overlaps existing flashSales with same item-lots",
+			)
+		}
+
+		if isHeld {
+			storedSale, err = c.holdForApproval(
+				collection, auditColl, tx, event, flashSale,
+			)
+			return err
+		}
+		if isDraft {
+			storedSale, err = c.insertSale(
+				collection, auditColl, tx, event, flashSale,
+			)
+			return err
+		}
+		e := c.validationEvent(uuid, cid, marshalItems)
+		err = c.publishEvent(collection, tx, e)
+		if err != nil {
+			err = errors.Wrap(err, "Error publishing validation-Event")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Insert")
 		log.Println(err)
+		var conflictResult []byte
+		if errorCode == SaleConflictError {
+			conflictResult, _ = json.Marshal(&saleConflictResult{
+				ConflictingFlashSaleIDs: conflictIDs,
+			})
+		}
 		return &model.Document{
 			AggregateID:   event.AggregateID,
 			CorrelationID: event.CorrelationID,
 			Error:         err.Error(),
-			ErrorCode:     InternalError,
+			ErrorCode:     errorCode,
 			EventAction:   event.EventAction,
+			Result:        conflictResult,
 			ServiceAction: event.ServiceAction,
 			UUID:          event.UUID,
 		}
 	}
+	if storedSale == nil {
+		return nil
+	}
 
-	_, err = collection.FindOne(map[string]interface{}{
-		"flashSaleID": flashSale.FlashSaleID.String(),
-	})
-	if err == nil {
-		err = errors.New("the flashSale is already inserted")
+	result, err := json.Marshal(storedSale)
+	if err != nil {
+		err = errors.Wrap(err, "Insert: Error marshalling stored FlashSale")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -136,43 +209,102 @@ func flashSaleCreated(collection *mongo.Collection, event *model.Event) *model.D
 			UUID:          event.UUID,
 		}
 	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// insertSale inserts the FlashSale as is, without validating its items with the
+// inventory, and writes its AuditRecord. The inserted FlashSale is returned.
+func (c *ExecContext) insertSale(
+	collection *mongo.Collection,
+	auditColl *mongo.Collection,
+	tx *txOptions,
+	event *model.Event,
+	flashSale *FlashSale,
+) (*FlashSale, error) {
+	insertedSale := *flashSale
+	insertResult, err := tx.insertOne(collection, insertedSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error Inserting FlashSale into Database")
+		return nil, err
+	}
+	if insertedID, ok := insertResult.InsertedID.(objectid.ObjectID); ok {
+		insertedSale.ID = insertedID
+	}
+	err = c.writeAudit(auditColl, tx, event, nil, map[objectid.ObjectID]*FlashSale{
+		insertedSale.ID: &insertedSale,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &insertedSale, nil
+}
 
-	mm := map[string]interface{}{}
-	json.Unmarshal(marshalItems, &mm)
-	e := model.Event{
+// validationEvent returns the Event for validating the items of FlashSale with
+// the inventory. The inventory responds with a "flashSaleValidated" Event, which
+// carries the service-user set as UserUUID here.
+func (c *ExecContext) validationEvent(
+	uuid uuuid.UUID,
+	cid uuuid.UUID,
+	marshalSale []byte,
+) *model.Event {
+	return &model.Event{
 		AggregateID:   2,
 		CorrelationID: cid,
 		EventAction:   "update",
 		ServiceAction: "createFlashSale",
-		Data:          marshalItems,
+		Data:          marshalSale,
 		NanoTime:      time.Now().UnixNano(),
+		UserUUID:      c.serviceUser(),
 		UUID:          uuid,
 		Version:       0,
 		YearBucket:    2018,
 	}
+}
 
-	if producer == nil {
-		kafkaBrokersStr := os.Getenv("KAFKA_BROKERS")
-		producer, err = kafka.NewProducer(&kafka.ProducerConfig{
-			KafkaBrokers: *commonutil.ParseHosts(kafkaBrokersStr),
-		})
-		if err != nil {
-			err = errors.Wrap(err, "ValidateFlashSale: Error creating producer")
-			log.Println(err)
-			return &model.Document{
-				AggregateID:   event.AggregateID,
-				CorrelationID: event.CorrelationID,
-				Error:         err.Error(),
-				ErrorCode:     InternalError,
-				EventAction:   event.EventAction,
-				ServiceAction: event.ServiceAction,
-				UUID:          event.UUID,
-			}
+// createDerivedSale runs a FlashSale derived from another entity, such as a template,
+// through the same validations and flow as a "flashSaleCreated" event.
+// The derived FlashSale is returned as Document-result if it passes the validations.
+func createDerivedSale(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+	flashSale *FlashSale,
+) *model.Document {
+	marshalSale, err := json.Marshal(flashSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling derived FlashSale")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
 		}
 	}
 
-	topic := os.Getenv("KAFKA_PRODUCER_EVENT_TOPIC")
-	me, _ := json.Marshal(e)
-	producer.Input() <- kafka.CreateMessage(topic, me)
-	return nil
+	createEvent := *event
+	createEvent.Data = marshalSale
+	doc := flashSaleCreated(c, collection, &createEvent)
+	if doc != nil {
+		return doc
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        marshalSale,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
 }
@@ -0,0 +1,166 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// templateSaleRequest is the Event-data for creating a FlashSale from a template.
+// The optional fields override the values from the template.
+type templateSaleRequest struct {
+	TemplateID  string `json:"templateID"`
+	FlashSaleID string `json:"flashSaleID,omitempty"`
+	// ItemIDs is the subset of template-items to include in the FlashSale.
+	ItemIDs   []string `json:"itemIDs,omitempty"`
+	StartTime int64    `json:"startTime,omitempty"`
+	EndTime   int64    `json:"endTime,omitempty"`
+	Status    string   `json:"status,omitempty"`
+}
+
+func flashSaleFromTemplate(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	req := &templateSaleRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "FromTemplate: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if req.TemplateID == "" {
+		err = errors.New("missing TemplateID")
+		err = errors.Wrap(err, "FromTemplate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	tplCollection, err := templateCollection(collection, c.cfg.Mongo.TemplateCollection)
+	var findResult interface{}
+	if err == nil {
+		findResult, err = tplCollection.FindOne(map[string]interface{}{
+			"templateID": req.TemplateID,
+		})
+	}
+	if err != nil {
+		err = errors.Wrap(err, "FromTemplate: Error finding Template")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	template, assertOK := findResult.(*FlashSaleTemplate)
+	if !assertOK {
+		err = errors.New("error asserting find-result to FlashSaleTemplate")
+		err = errors.Wrap(err, "FromTemplate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	flashSale, err := saleFromTemplate(template, req, event.UUID, time.Now())
+	if err != nil {
+		err = errors.Wrap(err, "FromTemplate")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return createDerivedSale(c, collection, event, flashSale)
+}
+
+// saleFromTemplate creates a FlashSale from the template with overrides from request.
+// Without a FlashSaleID in request, the FlashSaleID is derived from eventUUID.
+func saleFromTemplate(
+	template *FlashSaleTemplate,
+	req *templateSaleRequest,
+	eventUUID uuuid.UUID,
+	now time.Time,
+) (*FlashSale, error) {
+	flashSaleID := eventSaleID(eventUUID)
+	if req.FlashSaleID != "" {
+		var err error
+		flashSaleID, err = uuuid.FromString(req.FlashSaleID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing FlashSaleID")
+			return nil, err
+		}
+	}
+
+	items := template.Items
+	if len(req.ItemIDs) > 0 {
+		templateItems := map[string][]SoldItem{}
+		for _, item := range template.Items {
+			itemID := item.ItemID.String()
+			templateItems[itemID] = append(templateItems[itemID], item)
+		}
+
+		items = make([]SoldItem, 0)
+		for _, itemID := range req.ItemIDs {
+			if templateItems[itemID] == nil {
+				return nil, errors.Errorf("item %s is not part of template", itemID)
+			}
+			items = append(items, templateItems[itemID]...)
+		}
+	}
+
+	endTime := req.EndTime
+	if endTime == 0 && req.StartTime != 0 && template.Duration > 0 {
+		endTime = req.StartTime + template.Duration
+	}
+	status := req.Status
+	if status == "" {
+		status = StatusActive
+	}
+
+	return &FlashSale{
+		FlashSaleID: flashSaleID,
+		Items:       items,
+		StartTime:   req.StartTime,
+		EndTime:     endTime,
+		Status:      status,
+		Timestamp:   now.Unix(),
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
 	"github.com/pkg/errors"
 )
 
@@ -24,6 +25,7 @@ type flashSaleValidationResp struct {
 }
 
 func flashSaleValidated(
+	c *ExecContext,
 	collection *mongo.Collection,
 	event *model.Event,
 ) *model.Document {
@@ -43,9 +45,9 @@ func flashSaleValidated(
 		}
 	}
 
-	_, err = collection.InsertOne(validResp.OriginalRequest)
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
 	if err != nil {
-		err = errors.Wrap(err, "Insert: Error Inserting FlashSale into Database")
+		err = errors.Wrap(err, "Insert: Error getting audit-collection")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -72,8 +74,7 @@ func flashSaleValidated(
 			UUID:          event.UUID,
 		}
 	}
-
-	return &model.Document{
+	resultDoc := &model.Document{
 		AggregateID:   event.AggregateID,
 		CorrelationID: event.CorrelationID,
 		EventAction:   event.EventAction,
@@ -81,4 +82,86 @@ func flashSaleValidated(
 		ServiceAction: event.ServiceAction,
 		UUID:          event.UUID,
 	}
+
+	// The response is published through outbox, so it is
+	// published if and only if the FlashSale is inserted
+	insertedSale := validResp.OriginalRequest
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		// Approved and draft FlashSales are already stored, and are only activated
+		storedSales, err := findSalesByID(collection, map[string]interface{}{
+			"flashSaleID": insertedSale.FlashSaleID.String(),
+			"status": map[string]interface{}{
+				"$in": []string{StatusApproved, StatusDraft},
+			},
+		}, tx.find()...)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding stored FlashSale")
+			return err
+		}
+		if len(storedSales) > 0 {
+			return c.activateStoredSales(
+				collection, auditColl, tx, event, resultDoc, storedSales,
+			)
+		}
+
+		insertResult, err := tx.insertOne(collection, insertedSale)
+		if err != nil {
+			err = errors.Wrap(err, "Error Inserting FlashSale into Database")
+			return err
+		}
+		if insertedID, ok := insertResult.InsertedID.(objectid.ObjectID); ok {
+			insertedSale.ID = insertedID
+		}
+		err = c.writeAudit(auditColl, tx, event, nil, map[objectid.ObjectID]*FlashSale{
+			insertedSale.ID: &insertedSale,
+		})
+		if err != nil {
+			return err
+		}
+		return c.publishDocument(collection, tx, resultDoc)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Insert")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return nil
+}
+
+// activateStoredSales sets StatusActive on the approved or draft FlashSales, and
+// publishes the response-Document.
+func (c *ExecContext) activateStoredSales(
+	collection *mongo.Collection,
+	auditColl *mongo.Collection,
+	tx *txOptions,
+	event *model.Event,
+	resultDoc *model.Document,
+	storedSales map[objectid.ObjectID]*FlashSale,
+) error {
+	filter := saleIDsFilter(storedSales)
+	_, err := tx.updateMany(collection, filter, map[string]interface{}{
+		"status": StatusActive,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error activating stored FlashSale")
+		return err
+	}
+	activeSales, err := findSalesByID(collection, filter, tx.find()...)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding activated FlashSale")
+		return err
+	}
+	err = c.writeAudit(auditColl, tx, event, storedSales, activeSales)
+	if err != nil {
+		return err
+	}
+	return c.publishDocument(collection, tx, resultDoc)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/uuuid"
 	. "github.com/onsi/ginkgo"
@@ -18,6 +19,8 @@ func TestFlashSale(t *testing.T) {
 }
 
 var _ = Describe("FlashSaleAggregate", func() {
+	c := NewExecContext(config.Default())
+
 	Describe("delete", func() {
 		It("should return error if filter is empty", func() {
 			uuid, err := uuuid.NewV4()
@@ -38,7 +41,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Delete(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -94,7 +97,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Insert(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -125,7 +128,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Insert(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -156,7 +159,38 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Insert(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
+			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
+			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
+			Expect(kr.Error).ToNot(BeEmpty())
+			Expect(kr.ErrorCode).To(Equal(int16(InternalError)))
+			Expect(kr.UUID).To(Equal(mockEvent.UUID))
+		})
+		It("should return error if endTime is not after startTime", func() {
+			flashSale.StartTime = time.Now().Unix()
+			flashSale.EndTime = flashSale.StartTime - 3600
+			marshalFlashSale, err := json.Marshal(flashSale)
+			Expect(err).ToNot(HaveOccurred())
+
+			uuid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			cid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			uid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+
+			mockEvent := &model.Event{
+				EventAction:   "insert",
+				CorrelationID: cid,
+				AggregateID:   1,
+				Data:          marshalFlashSale,
+				NanoTime:      time.Now().UnixNano(),
+				UserUUID:      uid,
+				UUID:          uuid,
+				Version:       3,
+				YearBucket:    2018,
+			}
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -181,7 +215,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 			marshalArgs, err := json.Marshal(updateArgs)
 			Expect(err).ToNot(HaveOccurred())
 			mockEvent := &model.Event{
-				EventAction:   "delete",
+				EventAction:   "update",
 				CorrelationID: cid,
 				AggregateID:   AggregateID,
 				Data:          marshalArgs,
@@ -191,7 +225,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -216,7 +250,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 			marshalArgs, err := json.Marshal(updateArgs)
 			Expect(err).ToNot(HaveOccurred())
 			mockEvent := &model.Event{
-				EventAction:   "delete",
+				EventAction:   "update",
 				CorrelationID: cid,
 				AggregateID:   AggregateID,
 				Data:          marshalArgs,
@@ -226,7 +260,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -253,7 +287,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 			marshalArgs, err := json.Marshal(updateArgs)
 			Expect(err).ToNot(HaveOccurred())
 			mockEvent := &model.Event{
-				EventAction:   "delete",
+				EventAction:   "update",
 				CorrelationID: cid,
 				AggregateID:   AggregateID,
 				Data:          marshalArgs,
@@ -263,7 +297,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -290,7 +324,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 			marshalArgs, err := json.Marshal(updateArgs)
 			Expect(err).ToNot(HaveOccurred())
 			mockEvent := &model.Event{
-				EventAction:   "delete",
+				EventAction:   "update",
 				CorrelationID: cid,
 				AggregateID:   AggregateID,
 				Data:          marshalArgs,
@@ -300,7 +334,7 @@ var _ = Describe("FlashSaleAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, mockEvent)
+			kr := c.Handle(nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -0,0 +1,84 @@
+package flashsale
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+)
+
+// uuidPattern matches the string-form of UUIDs, as stored in the collection.
+const uuidPattern = "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-" +
+	"[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+
+// schemaRequiredFields are the BSON-fields required for each type,
+// same as the fields required by ValidateFlashSale.
+var schemaRequiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(FlashSale{}): []string{"flashSaleID", "items", "timestamp"},
+	reflect.TypeOf(SoldItem{}):  []string{"itemID", "weight"},
+}
+
+// JSONSchema returns the MongoDB $jsonSchema for FlashSale documents, generated
+// from the BSON-fields of FlashSale and SoldItem. Numeric fields accept any
+// BSON-number, since the numbers in update-events are decoded from JSON.
+func JSONSchema() map[string]interface{} {
+	return typeSchema(reflect.TypeOf(FlashSale{}))
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(uuuid.UUID{}):
+		return map[string]interface{}{
+			"bsonType": "string",
+			"pattern":  uuidPattern,
+		}
+	case reflect.TypeOf(objectid.ObjectID{}):
+		return map[string]interface{}{
+			"bsonType": "objectId",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{
+			"bsonType": "string",
+		}
+	case reflect.Bool:
+		return map[string]interface{}{
+			"bsonType": "bool",
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return map[string]interface{}{
+			"bsonType": "number",
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"bsonType": "array",
+			"items":    typeSchema(t.Elem()),
+		}
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("bson"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = typeSchema(field.Type)
+		}
+		schema := map[string]interface{}{
+			"bsonType":   "object",
+			"properties": properties,
+		}
+		if required, exists := schemaRequiredFields[t]; exists {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
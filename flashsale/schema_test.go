@@ -0,0 +1,50 @@
+package flashsale
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONSchema", func() {
+	var (
+		schema     map[string]interface{}
+		properties map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		schema = JSONSchema()
+		properties = schema["properties"].(map[string]interface{})
+	})
+
+	It("should require the FlashSale fields required by ValidateFlashSale", func() {
+		Expect(schema["bsonType"]).To(Equal("object"))
+		Expect(schema["required"]).To(ConsistOf("flashSaleID", "items", "timestamp"))
+	})
+
+	It("should use BSON-types of the stored fields", func() {
+		Expect(properties["_id"]).To(Equal(map[string]interface{}{
+			"bsonType": "objectId",
+		}))
+		Expect(properties["flashSaleID"]).To(HaveKeyWithValue("bsonType", "string"))
+		Expect(properties["flashSaleID"]).To(HaveKey("pattern"))
+		Expect(properties["startTime"]).To(Equal(map[string]interface{}{
+			"bsonType": "number",
+		}))
+		Expect(properties["status"]).To(Equal(map[string]interface{}{
+			"bsonType": "string",
+		}))
+	})
+
+	It("should generate schema for SoldItems", func() {
+		items := properties["items"].(map[string]interface{})
+		Expect(items["bsonType"]).To(Equal("array"))
+
+		item := items["items"].(map[string]interface{})
+		Expect(item["required"]).To(ConsistOf("itemID", "weight"))
+		itemProperties := item["properties"].(map[string]interface{})
+		Expect(itemProperties).To(HaveLen(7))
+		Expect(itemProperties["weight"]).To(Equal(map[string]interface{}{
+			"bsonType": "number",
+		}))
+	})
+})
@@ -0,0 +1,162 @@
+package flashsale
+
+import (
+	"encoding/json"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// FlashSaleTemplate defines a reusable set of items from which FlashSales are created.
+type FlashSaleTemplate struct {
+	ID         objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	TemplateID uuuid.UUID        `bson:"templateID,omitempty" json:"templateID,omitempty"`
+	Name       string            `bson:"name,omitempty" json:"name,omitempty"`
+	Items      []SoldItem        `bson:"items,omitempty" json:"items,omitempty"`
+	// Duration is the default length of time-window, in seconds,
+	// for FlashSales created from this template.
+	Duration  int64 `bson:"duration,omitempty" json:"duration,omitempty"`
+	Timestamp int64 `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+}
+
+// Same reasons as flashSaleBSON
+type flashSaleTemplateBSON struct {
+	ID         objectid.ObjectID `bson:"_id,omitempty"`
+	TemplateID string            `bson:"templateID,omitempty"`
+	Name       string            `bson:"name,omitempty"`
+	Items      []soldItemXSON    `bson:"items,omitempty"`
+	Duration   int64             `bson:"duration,omitempty"`
+	Timestamp  int64             `bson:"timestamp,omitempty"`
+}
+
+type flashSaleTemplateJSON struct {
+	ID         string         `json:"_id,omitempty"`
+	TemplateID string         `json:"templateID,omitempty"`
+	Name       string         `json:"name,omitempty"`
+	Items      []soldItemXSON `json:"items,omitempty"`
+	Duration   int64          `json:"duration,omitempty"`
+	Timestamp  int64          `json:"timestamp,omitempty"`
+}
+
+// templateCollection returns the collection with provided name storing
+// FlashSaleTemplates.
+func templateCollection(
+	aggCollection *mongo.Collection,
+	name string,
+) (*mongo.Collection, error) {
+	indexConfigs := []mongo.IndexConfig{
+		mongo.IndexConfig{
+			ColumnConfig: []mongo.IndexColumnConfig{
+				mongo.IndexColumnConfig{
+					Name: "templateID",
+				},
+			},
+			IsUnique: true,
+			Name:     "templateID_index",
+		},
+	}
+	return siblingCollection(aggCollection, name, &FlashSaleTemplate{}, indexConfigs)
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (t FlashSaleTemplate) MarshalBSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"name":      t.Name,
+		"duration":  t.Duration,
+		"timestamp": t.Timestamp,
+	}
+	if t.ID != objectid.NilObjectID {
+		in["_id"] = t.ID
+	}
+	if t.TemplateID != (uuuid.UUID{}) {
+		in["templateID"] = t.TemplateID.String()
+	}
+	if len(t.Items) > 0 {
+		in["items"] = soldItemsToMaps(t.Items)
+	}
+
+	return bson.Marshal(in)
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (t *FlashSaleTemplate) MarshalJSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"name":      t.Name,
+		"duration":  t.Duration,
+		"timestamp": t.Timestamp,
+	}
+	if t.ID != objectid.NilObjectID {
+		in["_id"] = t.ID.Hex()
+	}
+	if t.TemplateID != (uuuid.UUID{}) {
+		in["templateID"] = t.TemplateID.String()
+	}
+	if len(t.Items) > 0 {
+		in["items"] = soldItemsToMaps(t.Items)
+	}
+
+	return json.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (t *FlashSaleTemplate) UnmarshalBSON(in []byte) error {
+	tb := &flashSaleTemplateBSON{}
+	err := bson.Unmarshal(in, tb)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error")
+		return err
+	}
+
+	t.ID = tb.ID
+	t.Name = tb.Name
+	t.Duration = tb.Duration
+	t.Timestamp = tb.Timestamp
+
+	t.TemplateID, err = uuuid.FromString(tb.TemplateID)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing TemplateID")
+		return err
+	}
+	t.Items, err = soldItemsFromXSON(tb.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalBSON")
+		return err
+	}
+	return nil
+}
+
+// UnmarshalJSON returns JSON-type from bytes.
+func (t *FlashSaleTemplate) UnmarshalJSON(in []byte) error {
+	tj := &flashSaleTemplateJSON{}
+	err := json.Unmarshal(in, tj)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON Error")
+		return err
+	}
+
+	t.Name = tj.Name
+	t.Duration = tj.Duration
+	t.Timestamp = tj.Timestamp
+
+	if tj.ID != "" && tj.ID != objectid.NilObjectID.String() {
+		t.ID, err = objectid.FromHex(tj.ID)
+		if err != nil {
+			err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing ObjectID")
+			return err
+		}
+	}
+	t.TemplateID, err = uuuid.FromString(tj.TemplateID)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON Error: Error parsing TemplateID")
+		return err
+	}
+	t.Items, err = soldItemsFromXSON(tj.Items)
+	if err != nil {
+		err = errors.Wrap(err, "UnmarshalJSON")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+func templateCreated(
+	c *ExecContext,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	template := &FlashSaleTemplate{}
+	err := json.Unmarshal(event.Data, template)
+	if err != nil {
+		err = errors.Wrap(err, "TemplateCreated: Error while unmarshalling Event-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	if template.TemplateID == (uuuid.UUID{}) {
+		err = errors.New("missing TemplateID")
+	} else if template.Name == "" {
+		err = errors.New("missing Name")
+	} else if len(template.Items) == 0 {
+		err = errors.New("missing TemplateItems")
+	} else if template.Duration < 0 {
+		err = errors.New("Duration cannot be negative")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "TemplateCreated")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	tplCollection, err := templateCollection(collection, c.cfg.Mongo.TemplateCollection)
+	if err != nil {
+		err = errors.Wrap(err, "TemplateCreated")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	_, err = tplCollection.InsertOne(template)
+	if err != nil {
+		err = errors.Wrap(err, "TemplateCreated: Error Inserting Template into Database")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     DatabaseError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(template)
+	if err != nil {
+		err = errors.Wrap(err, "TemplateCreated: Error marshalling Template")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
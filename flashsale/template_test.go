@@ -0,0 +1,81 @@
+package flashsale
+
+import (
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FlashSaleTemplate", func() {
+	var template *FlashSaleTemplate
+
+	BeforeEach(func() {
+		templateID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID1, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID2, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		template = &FlashSaleTemplate{
+			TemplateID: templateID,
+			Name:       "test-template",
+			Items: []SoldItem{
+				SoldItem{
+					ItemID: itemID1,
+					Weight: 12.24,
+					Lot:    "test-lot-1",
+				},
+				SoldItem{
+					ItemID: itemID2,
+					Weight: 3.5,
+					Lot:    "test-lot-2",
+				},
+			},
+			Duration: 3600,
+		}
+	})
+
+	It("should apply overrides and template-duration", func() {
+		now := time.Now()
+		req := &templateSaleRequest{
+			TemplateID: template.TemplateID.String(),
+			ItemIDs:    []string{template.Items[1].ItemID.String()},
+			StartTime:  now.Unix(),
+		}
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		flashSale, err := saleFromTemplate(template, req, eventUUID, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flashSale.FlashSaleID).To(Equal(eventSaleID(eventUUID)))
+		Expect(flashSale.Items).To(Equal([]SoldItem{template.Items[1]}))
+		Expect(flashSale.EndTime).To(Equal(now.Unix() + 3600))
+		Expect(flashSale.Status).To(Equal(StatusActive))
+	})
+
+	It("should return error if item-subset is not part of template", func() {
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		req := &templateSaleRequest{
+			TemplateID: template.TemplateID.String(),
+			ItemIDs:    []string{itemID.String()},
+		}
+		_, err = saleFromTemplate(template, req, template.TemplateID, time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should derive the same FlashSaleID for the same Event", func() {
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		req := &templateSaleRequest{
+			TemplateID: template.TemplateID.String(),
+		}
+		flashSale1, err := saleFromTemplate(template, req, eventUUID, time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		flashSale2, err := saleFromTemplate(template, req, eventUUID, time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flashSale1.FlashSaleID).To(Equal(flashSale2.FlashSaleID))
+	})
+})
@@ -0,0 +1,107 @@
+package flashsale
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// localTimeLayout is the layout of local times without a UTC-offset,
+// which are interpreted in the TimeZone of FlashSale.
+const localTimeLayout = "2006-01-02T15:04:05"
+
+// loadTimeZone returns the Location for the IANA time zone name,
+// such as "America/Toronto". A blank name is UTC.
+func loadTimeZone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		err = errors.Wrapf(err, "invalid TimeZone: %s", name)
+		return nil, err
+	}
+	return loc, nil
+}
+
+// parseLocalTime returns the Unix-time for the time in RFC3339 format, or in
+// local format ("2006-01-02T15:04:05") interpreted in the Location.
+// See resolveLocalTime for handling of DST-transitions.
+func parseLocalTime(value string, loc *time.Location) (int64, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t.Unix(), nil
+	}
+	wall, err := time.Parse(localTimeLayout, value)
+	if err != nil {
+		err = errors.Errorf(
+			"invalid time: %s, must be RFC3339 or of format %s", value, localTimeLayout,
+		)
+		return 0, err
+	}
+	t, err = resolveLocalTime(wall, loc)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// resolveLocalTime returns the time at which the clocks in Location show the
+// wall-clock time (the date and time of wall, ignoring its Location).
+// A wall-clock time repeated when clocks are set back (such as at the end of DST)
+// resolves to its first occurrence. An error is returned for wall-clock times
+// skipped when clocks are set forward (such as at the start of DST), since
+// those never occur in the Location.
+func resolveLocalTime(wall time.Time, loc *time.Location) (time.Time, error) {
+	wallUTC := time.Date(
+		wall.Year(), wall.Month(), wall.Day(),
+		wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(),
+		time.UTC,
+	)
+
+	// The UTC-offsets in effect around the wall-clock time. Transitions
+	// are assumed to be at least a day apart.
+	var resolved time.Time
+	for _, probe := range []time.Duration{-24 * time.Hour, 24 * time.Hour} {
+		_, offset := wallUTC.Add(probe).In(loc).Zone()
+		t := wallUTC.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !sameWallClock(t, wallUTC) {
+			continue
+		}
+		if resolved.IsZero() || t.Before(resolved) {
+			resolved = t
+		}
+	}
+	if resolved.IsZero() {
+		return time.Time{}, errors.Errorf(
+			"local time %s does not exist in time zone %s due to DST-transition",
+			wallUTC.Format(localTimeLayout), loc,
+		)
+	}
+	return resolved, nil
+}
+
+func sameWallClock(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
+
+// formatLocalTime formats the Unix-time in RFC3339 format in the Location.
+func formatLocalTime(unix int64, loc *time.Location) string {
+	return time.Unix(unix, 0).In(loc).Format(time.RFC3339)
+}
+
+// daysUntil returns the number of calendar-days in Location from "from" until "to",
+// rounded up. Days are counted on the local calendar, so days with DST-transitions
+// (which are 23 or 25 hours long) count as one day.
+func daysUntil(from time.Time, to time.Time, loc *time.Location) int {
+	from = from.In(loc)
+	if !from.Before(to) {
+		// Past times are rounded up to whole (negative) days
+		return -int(from.Sub(to).Hours() / 24)
+	}
+	days := 1
+	for from.AddDate(0, 0, days).Before(to) {
+		days++
+	}
+	return days
+}
@@ -0,0 +1,216 @@
+package flashsale
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimeZone", func() {
+	var toronto *time.Location
+
+	BeforeEach(func() {
+		var err error
+		toronto, err = loadTimeZone("America/Toronto")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("parseLocalTime", func() {
+		It("should interpret local times in time zone", func() {
+			unix, err := parseLocalTime("2018-07-07T09:00:00", toronto)
+			Expect(err).ToNot(HaveOccurred())
+			// EDT is UTC-4
+			Expect(time.Unix(unix, 0).UTC()).To(Equal(
+				time.Date(2018, 7, 7, 13, 0, 0, 0, time.UTC),
+			))
+
+			unix, err = parseLocalTime("2018-12-08T09:00:00", toronto)
+			Expect(err).ToNot(HaveOccurred())
+			// EST is UTC-5
+			Expect(time.Unix(unix, 0).UTC()).To(Equal(
+				time.Date(2018, 12, 8, 14, 0, 0, 0, time.UTC),
+			))
+		})
+
+		It("should use the UTC-offset of RFC3339 times", func() {
+			unix, err := parseLocalTime("2018-07-07T09:00:00+02:00", toronto)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(time.Unix(unix, 0).UTC()).To(Equal(
+				time.Date(2018, 7, 7, 7, 0, 0, 0, time.UTC),
+			))
+		})
+
+		It("should return error for local times skipped by DST-transition", func() {
+			// Clocks moved from 02:00 to 03:00 on 11 March 2018
+			_, err := parseLocalTime("2018-03-11T02:30:00", toronto)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should use first occurrence of local times repeated by DST", func() {
+			// Clocks moved from 02:00 back to 01:00 on 4 November 2018
+			unix, err := parseLocalTime("2018-11-04T01:30:00", toronto)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(time.Unix(unix, 0).UTC()).To(Equal(
+				time.Date(2018, 11, 4, 5, 30, 0, 0, time.UTC),
+			))
+		})
+
+		It("should return error for invalid times", func() {
+			_, err := parseLocalTime("Saturday 9am", toronto)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("daysUntil", func() {
+		It("should count days on the local calendar across DST-transitions", func() {
+			// 4 November 2018 is 25 hours long in Toronto
+			from := time.Date(2018, 11, 3, 12, 0, 0, 0, toronto)
+			to := time.Date(2018, 11, 5, 12, 0, 0, 0, toronto)
+			Expect(to.Sub(from).Hours()).To(Equal(float64(49)))
+			Expect(daysUntil(from, to, toronto)).To(Equal(2))
+
+			// 11 March 2018 is 23 hours long in Toronto
+			from = time.Date(2018, 3, 10, 12, 0, 0, 0, toronto)
+			to = time.Date(2018, 3, 12, 12, 0, 0, 0, toronto)
+			Expect(daysUntil(from, to, toronto)).To(Equal(2))
+		})
+
+		It("should round up partial days", func() {
+			from := time.Date(2018, 7, 7, 12, 0, 0, 0, toronto)
+			to := time.Date(2018, 7, 8, 13, 0, 0, 0, toronto)
+			Expect(daysUntil(from, to, toronto)).To(Equal(2))
+			Expect(daysUntil(from, from, toronto)).To(Equal(0))
+		})
+	})
+
+	Describe("FlashSale JSON", func() {
+		It("should set StartTime and EndTime from local times", func() {
+			sale := &FlashSale{}
+			err := json.Unmarshal([]byte(`{
+				"flashSaleID": "6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e",
+				"timeZone": "America/Toronto",
+				"startTimeLocal": "2018-11-03T09:00:00",
+				"endTimeLocal": "2018-11-04T09:00:00"
+			}`), sale)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sale.StartTime).To(Equal(
+				time.Date(2018, 11, 3, 13, 0, 0, 0, time.UTC).Unix(),
+			))
+			// The time zone changes from EDT to EST during the sale
+			Expect(sale.EndTime).To(Equal(
+				time.Date(2018, 11, 4, 14, 0, 0, 0, time.UTC).Unix(),
+			))
+		})
+
+		It("should render times in UTC and in time zone", func() {
+			sale := &FlashSale{
+				StartTime: time.Date(2018, 11, 3, 13, 0, 0, 0, time.UTC).Unix(),
+				TimeZone:  "America/Toronto",
+			}
+			marshalSale, err := json.Marshal(sale)
+			Expect(err).ToNot(HaveOccurred())
+
+			rendered := map[string]interface{}{}
+			err = json.Unmarshal(marshalSale, &rendered)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rendered["startTimeUTC"]).To(Equal("2018-11-03T13:00:00Z"))
+			Expect(rendered["startTimeLocal"]).To(Equal("2018-11-03T09:00:00-04:00"))
+			Expect(rendered["timeZone"]).To(Equal("America/Toronto"))
+		})
+
+		It("should keep TimeZone and times through a JSON round-trip", func() {
+			flashSaleID, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			sale := &FlashSale{
+				FlashSaleID: flashSaleID,
+				StartTime:   time.Date(2018, 11, 3, 13, 0, 0, 0, time.UTC).Unix(),
+				EndTime:     time.Date(2018, 11, 4, 14, 0, 0, 0, time.UTC).Unix(),
+				TimeZone:    "America/Toronto",
+			}
+			marshalSale, err := json.Marshal(sale)
+			Expect(err).ToNot(HaveOccurred())
+
+			unmarshalSale := &FlashSale{}
+			err = json.Unmarshal(marshalSale, unmarshalSale)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unmarshalSale.TimeZone).To(Equal(sale.TimeZone))
+			Expect(unmarshalSale.StartTime).To(Equal(sale.StartTime))
+			Expect(unmarshalSale.EndTime).To(Equal(sale.EndTime))
+		})
+
+		It("should return error if local time does not match StartTime", func() {
+			sale := &FlashSale{}
+			err := json.Unmarshal([]byte(`{
+				"flashSaleID": "6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e",
+				"timeZone": "America/Toronto",
+				"startTime": 1541250000,
+				"startTimeLocal": "2018-11-03T10:00:00"
+			}`), sale)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return error for invalid time zone", func() {
+			sale := &FlashSale{}
+			err := json.Unmarshal([]byte(`{
+				"flashSaleID": "6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e",
+				"timeZone": "Mars/Olympus_Mons",
+				"startTimeLocal": "2018-11-03T10:00:00"
+			}`), sale)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("normalizeLocalTimes", func() {
+		var sales map[objectid.ObjectID]*FlashSale
+
+		BeforeEach(func() {
+			sales = map[objectid.ObjectID]*FlashSale{
+				objectid.New(): &FlashSale{TimeZone: "America/Toronto"},
+				objectid.New(): &FlashSale{TimeZone: "America/Toronto"},
+			}
+		})
+
+		It("should convert local times in time zone of FlashSales", func() {
+			update := map[string]interface{}{
+				"startTimeLocal": "2018-11-03T09:00:00",
+				"endTimeLocal":   "2018-11-03T12:00:00",
+			}
+			Expect(normalizeLocalTimes(update, sales)).To(Succeed())
+			Expect(update).To(Equal(map[string]interface{}{
+				"startTime": int64(1541250000),
+				"endTime":   int64(1541260800),
+			}))
+		})
+
+		It("should convert local times in time zone from update", func() {
+			update := map[string]interface{}{
+				"timeZone":       "UTC",
+				"startTimeLocal": "2018-11-03T13:00:00",
+			}
+			Expect(normalizeLocalTimes(update, sales)).To(Succeed())
+			Expect(update["startTime"]).To(Equal(int64(1541250000)))
+			Expect(update).ToNot(HaveKey("startTimeLocal"))
+		})
+
+		It("should return error for FlashSales with different time zones", func() {
+			sales[objectid.New()] = &FlashSale{TimeZone: "Europe/Paris"}
+			update := map[string]interface{}{
+				"startTimeLocal": "2018-11-03T09:00:00",
+			}
+			Expect(normalizeLocalTimes(update, sales)).ToNot(Succeed())
+		})
+
+		It("should return error if local time does not match StartTime", func() {
+			update := map[string]interface{}{
+				"startTime":      int64(1541250000),
+				"startTimeLocal": "2018-11-03T10:00:00",
+			}
+			Expect(normalizeLocalTimes(update, sales)).ToNot(Succeed())
+		})
+	})
+})
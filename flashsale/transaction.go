@@ -0,0 +1,214 @@
+package flashsale
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// transactionsUnsupported is set once the server is found to not support
+// transactions, so further transactions directly use the fallback.
+var transactionsUnsupported int32
+
+// unsupportedTransactionErrors are the error-messages returned by servers
+// not supporting transactions, such as standalone servers.
+var unsupportedTransactionErrors = []string{
+	"transaction numbers are only allowed on a replica set member or mongos",
+	"transaction numbers are only allowed on storage engines that support",
+	"transactions are not supported",
+	"does not support transactions",
+	"standalone servers do not support transactions",
+}
+
+// txOptions provides the options for running reads and writes in a transaction.
+// The session is nil if the operations are not transactional.
+//
+// The Collection-wrapper only accepts options for reads, so the writes in a
+// transaction are run on the driver-collection with the session.
+type txOptions struct {
+	session *mgo.Session
+}
+
+func (t *txOptions) isTransactional() bool {
+	return t != nil && t.session != nil
+}
+
+func (t *txOptions) find() []findopt.Find {
+	if !t.isTransactional() {
+		return nil
+	}
+	return []findopt.Find{t.session}
+}
+
+// insertOne inserts the data into collection.
+func (t *txOptions) insertOne(
+	collection *mongo.Collection,
+	data interface{},
+) (*mgo.InsertOneResult, error) {
+	if !t.isTransactional() {
+		return collection.InsertOne(data)
+	}
+	doc, err := toBSONDocument(data)
+	if err != nil {
+		err = errors.Wrap(err, "InsertOne - BSON Convert Error")
+		return nil, err
+	}
+
+	ctx, cancel := newTxContext(collection)
+	defer cancel()
+	result, err := collection.Collection().InsertOne(ctx, doc, t.session)
+	if err != nil {
+		err = errors.Wrap(err, "InsertOne Error")
+	}
+	return result, err
+}
+
+// updateMany sets the update-fields on documents matching the filter.
+func (t *txOptions) updateMany(
+	collection *mongo.Collection,
+	filter interface{},
+	update interface{},
+) (*mgo.UpdateResult, error) {
+	if !t.isTransactional() {
+		return collection.UpdateMany(filter, update)
+	}
+	filterDoc, err := toBSONDocument(filter)
+	if err != nil {
+		err = errors.Wrap(err, "UpdateMany - BSON Convert Error for filter-argument")
+		return nil, err
+	}
+	updateDoc, err := toBSONDocument(map[string]interface{}{
+		"$set": update,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "UpdateMany - BSON Convert Error for update-argument")
+		return nil, err
+	}
+
+	ctx, cancel := newTxContext(collection)
+	defer cancel()
+	result, err := collection.Collection().UpdateMany(
+		ctx, filterDoc, updateDoc, t.session,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "UpdateMany Error")
+	}
+	return result, err
+}
+
+// deleteMany deletes the documents matching the filter.
+func (t *txOptions) deleteMany(
+	collection *mongo.Collection,
+	filter interface{},
+) (*mgo.DeleteResult, error) {
+	if !t.isTransactional() {
+		return collection.DeleteMany(filter)
+	}
+	doc, err := toBSONDocument(filter)
+	if err != nil {
+		err = errors.Wrap(err, "DeleteMany - BSON Convert Error")
+		return nil, err
+	}
+
+	ctx, cancel := newTxContext(collection)
+	defer cancel()
+	result, err := collection.Collection().DeleteMany(ctx, doc, t.session)
+	if err != nil {
+		err = errors.Wrap(err, "Deletion Error")
+	}
+	return result, err
+}
+
+// newTxContext returns a context with the timeout of the collection's connection.
+func newTxContext(collection *mongo.Collection) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(
+		context.Background(),
+		time.Duration(collection.Connection.Timeout)*time.Millisecond,
+	)
+}
+
+// toBSONDocument converts the data to a BSON-document, same as the
+// Collection-wrapper. A nil ObjectID is removed, so MongoDB generates one.
+func toBSONDocument(data interface{}) (*bson.Document, error) {
+	doc, err := bson.NewDocumentEncoder().EncodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	id := doc.Lookup("_id")
+	isObjectID := id != nil && id.Type() == bson.TypeObjectID
+	if isObjectID && id.ObjectID() == objectid.NilObjectID {
+		doc.Delete("_id")
+	}
+	return doc, nil
+}
+
+// isTransactionUnsupported checks if the error is caused by the server not
+// supporting transactions.
+func isTransactionUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := strings.ToLower(errors.Cause(err).Error())
+	for _, msg := range unsupportedTransactionErrors {
+		if strings.Contains(errStr, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// runInTransaction runs the operations in fn atomically in a transaction. The
+// operations must use the txOptions for being part of the transaction.
+//
+// Transactions require a replica set or sharded cluster. On servers not supporting
+// transactions, such as standalone servers, fn is run again without a transaction,
+// so the operations are run sequentially and an error in an operation leaves the
+// previous operations applied. Since the servers reject the first operation
+// in a transaction, fn is only run once on such servers.
+func runInTransaction(collection *mongo.Collection, fn func(*txOptions) error) error {
+	if atomic.LoadInt32(&transactionsUnsupported) == 1 {
+		return fn(&txOptions{})
+	}
+
+	ctx, cancel := newTxContext(collection)
+	defer cancel()
+
+	client := collection.Connection.Client.Database(collection.Database).Client()
+	session, err := client.StartSession()
+	if err != nil {
+		err = errors.Wrap(err, "Error starting session")
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	err = session.StartTransaction()
+	if err == nil {
+		err = fn(&txOptions{
+			session: session,
+		})
+		if err == nil {
+			err = session.CommitTransaction(ctx)
+		} else {
+			session.AbortTransaction(ctx)
+		}
+	}
+
+	if isTransactionUnsupported(err) {
+		atomic.StoreInt32(&transactionsUnsupported, 1)
+		log.Println(
+			"Transactions are not supported by MongoDB server, " +
+				"operations will be run without transactions",
+		)
+		return fn(&txOptions{})
+	}
+	return err
+}
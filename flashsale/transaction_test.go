@@ -0,0 +1,59 @@
+package flashsale
+
+import (
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("isTransactionUnsupported", func() {
+	It("should detect errors from standalone servers", func() {
+		err := errors.New(
+			"(IllegalOperation) Transaction numbers are only allowed " +
+				"on a replica set member or mongos",
+		)
+		Expect(isTransactionUnsupported(err)).To(BeTrue())
+	})
+
+	It("should detect wrapped errors", func() {
+		err := errors.New("Standalone servers do not support transactions")
+		err = errors.Wrap(err, "Error in UpdateMany")
+		Expect(isTransactionUnsupported(err)).To(BeTrue())
+	})
+
+	It("should not detect other errors", func() {
+		err := errors.New("E11000 duplicate key error collection")
+		Expect(isTransactionUnsupported(err)).To(BeFalse())
+		Expect(isTransactionUnsupported(nil)).To(BeFalse())
+	})
+})
+
+var _ = Describe("txOptions", func() {
+	It("should provide no options when operations are not transactional", func() {
+		tx := &txOptions{}
+		Expect(tx.isTransactional()).To(BeFalse())
+		Expect(tx.find()).To(BeEmpty())
+
+		var nilTx *txOptions
+		Expect(nilTx.isTransactional()).To(BeFalse())
+		Expect(nilTx.find()).To(BeEmpty())
+	})
+
+	It("should remove nil ObjectID when converting to BSON", func() {
+		doc, err := toBSONDocument(&OutboxEntry{
+			Topic:  "test-topic",
+			Status: OutboxStatusPending,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(doc.Lookup("_id")).To(BeNil())
+		Expect(doc.Lookup("topic").StringValue()).To(Equal("test-topic"))
+
+		id := objectid.New()
+		doc, err = toBSONDocument(map[string]interface{}{
+			"_id": id,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(doc.Lookup("_id").ObjectID()).To(Equal(id))
+	})
+})
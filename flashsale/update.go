@@ -10,21 +10,33 @@ import (
 
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
 	"github.com/pkg/errors"
 )
 
+// approvalFields are only set by the approval-flow, such as "activateFlashSale"
+// and "approveFlashSale" Events, and cannot be set by updates.
+var approvalFields = []string{"status", "reviewedBy", "reviewedAt", "submittedBy"}
+
 type flashSaleUpdate struct {
 	Filter map[string]interface{} `json:"filter"`
 	Update map[string]interface{} `json:"update"`
+	// ReturnDocuments includes the updated FlashSales in result.
+	ReturnDocuments bool `json:"returnDocuments,omitempty"`
 }
 
 type updateResult struct {
-	MatchedCount  int64 `json:"matchedCount,omitempty"`
-	ModifiedCount int64 `json:"modifiedCount,omitempty"`
+	MatchedCount  int64        `json:"matchedCount,omitempty"`
+	ModifiedCount int64        `json:"modifiedCount,omitempty"`
+	FlashSales    []*FlashSale `json:"flashSales,omitempty"`
+	// Truncated is true if the FlashSales exceeded the maximum returned FlashSales.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
-// Update handles "update" events.
-func Update(collection *mongo.Collection, event *model.Event) *model.Document {
+func (c *ExecContext) update(
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
 	flashSaleUpdate := &flashSaleUpdate{}
 
 	err := json.Unmarshal(event.Data, flashSaleUpdate)
@@ -88,6 +100,45 @@ func Update(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
+	for _, field := range approvalFields {
+		if _, ok := update[field]; ok {
+			err = errors.Errorf("%s cannot be updated", field)
+			err = errors.Wrap(err, "Update")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     InternalError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	if update["timeZone"] != nil {
+		timeZone, assertOK := update["timeZone"].(string)
+		if !assertOK {
+			err = errors.New("error asserting TimeZone")
+		} else {
+			_, err = loadTimeZone(timeZone)
+		}
+		if err != nil {
+			err = errors.Wrap(err, "Update")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     InternalError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
 	// Get Timestamp if present, assert it to Int64, and check if its 0.
 	if update["timestamp"] != nil {
 		timestamp, err := commonutil.AssertInt64(update["timestamp"])
@@ -114,9 +165,9 @@ func Update(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
-	updateStats, err := collection.UpdateMany(flashSaleUpdate.Filter, update)
+	auditColl, err := auditCollection(collection, c.cfg.Mongo.AuditCollection)
 	if err != nil {
-		err = errors.Wrap(err, "Update: Error in UpdateMany")
+		err = errors.Wrap(err, "Update: Error getting audit-collection")
 		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
@@ -129,10 +180,95 @@ func Update(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
-	result := &updateResult{
-		MatchedCount:  updateStats.MatchedCount,
-		ModifiedCount: updateStats.ModifiedCount,
+	result := &updateResult{}
+	var afterSales map[objectid.ObjectID]*FlashSale
+	// The FlashSales are found and checked for conflicts in the same transaction
+	// as the update, so they cannot change before being updated
+	var errorCode int16
+	var conflictIDs []string
+	err = runInTransaction(collection, func(tx *txOptions) error {
+		errorCode = DatabaseError
+		beforeSales, err := findSalesByID(
+			collection, flashSaleUpdate.Filter, tx.find()...,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding FlashSales to update")
+			return err
+		}
+
+		// Local times depend on the TimeZone of the found FlashSales
+		err = normalizeLocalTimes(update, beforeSales)
+		if err != nil {
+			errorCode = InternalError
+			return err
+		}
+
+		if changesSaleItems(update) {
+			updatedSales, err := applyUpdateToSales(beforeSales, update)
+			if err != nil {
+				errorCode = InternalError
+				return err
+			}
+			conflictIDs, err = findUpdateConflicts(collection, tx, updatedSales)
+			if err != nil {
+				return err
+			}
+			if len(conflictIDs) > 0 {
+				errorCode = SaleConflictError
+				return errors.New(
+					"update overlaps existing flashSales with same item-lots",
+				)
+			}
+		}
+
+		updateStats, err := tx.updateMany(
+			collection, flashSaleUpdate.Filter, update,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error in UpdateMany")
+			return err
+		}
+		result.MatchedCount = updateStats.MatchedCount
+		result.ModifiedCount = updateStats.ModifiedCount
+		if len(beforeSales) == 0 {
+			return nil
+		}
+
+		afterSales, err = findSalesByID(
+			collection, saleIDsFilter(beforeSales), tx.find()...,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error finding updated FlashSales")
+			return err
+		}
+		return c.writeAudit(auditColl, tx, event, beforeSales, afterSales)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Update")
+		log.Println(err)
+		var conflictResult []byte
+		if errorCode == SaleConflictError {
+			conflictResult, _ = json.Marshal(&saleConflictResult{
+				ConflictingFlashSaleIDs: conflictIDs,
+			})
+		}
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     errorCode,
+			EventAction:   event.EventAction,
+			Result:        conflictResult,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
 	}
+	if flashSaleUpdate.ReturnDocuments {
+		result.FlashSales, result.Truncated = returnedSales(
+			afterSales, c.cfg.Results.MaxReturnDocuments,
+		)
+	}
+
 	resultMarshal, err := json.Marshal(result)
 	if err != nil {
 		err = errors.Wrap(err, "Update: Error marshalling FlashSale Update-result")
@@ -157,3 +293,148 @@ func Update(collection *mongo.Collection, event *model.Event) *model.Document {
 		UUID:          event.UUID,
 	}
 }
+
+// normalizeLocalTimes replaces the "startTimeLocal" and "endTimeLocal" in update
+// with "startTime" and "endTime". The local times are in the "timeZone" from
+// update if set, else in the TimeZone of the FlashSales, which must then be
+// same for all FlashSales.
+func normalizeLocalTimes(
+	update map[string]interface{},
+	sales map[objectid.ObjectID]*FlashSale,
+) error {
+	if update["startTimeLocal"] == nil && update["endTimeLocal"] == nil {
+		return nil
+	}
+	timeZone, err := updatedTimeZone(update, sales)
+	if err != nil {
+		return err
+	}
+	loc, err := loadTimeZone(timeZone)
+	if err != nil {
+		return err
+	}
+
+	localTimes := []struct {
+		localKey string
+		key      string
+	}{
+		{"startTimeLocal", "startTime"},
+		{"endTimeLocal", "endTime"},
+	}
+	for _, lt := range localTimes {
+		if update[lt.localKey] == nil {
+			continue
+		}
+		value, assertOK := update[lt.localKey].(string)
+		if !assertOK {
+			return errors.Errorf("error asserting %s to string", lt.localKey)
+		}
+		unix, err := parseLocalTime(value, loc)
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing %s", lt.localKey)
+			return err
+		}
+		if update[lt.key] != nil {
+			t, err := commonutil.AssertInt64(update[lt.key])
+			if err != nil || t != unix {
+				return errors.Errorf("%s does not match %s", lt.localKey, lt.key)
+			}
+		}
+		update[lt.key] = unix
+		delete(update, lt.localKey)
+	}
+	return nil
+}
+
+// updatedTimeZone returns the TimeZone which the FlashSales have after the update.
+func updatedTimeZone(
+	update map[string]interface{},
+	sales map[objectid.ObjectID]*FlashSale,
+) (string, error) {
+	if update["timeZone"] != nil {
+		timeZone, assertOK := update["timeZone"].(string)
+		if !assertOK {
+			return "", errors.New("error asserting TimeZone")
+		}
+		return timeZone, nil
+	}
+
+	timeZone := ""
+	isFirst := true
+	for _, sale := range sales {
+		if !isFirst && sale.TimeZone != timeZone {
+			return "", errors.New(
+				"FlashSales have different TimeZones, " +
+					"local times require timeZone in update",
+			)
+		}
+		timeZone = sale.TimeZone
+		isFirst = false
+	}
+	return timeZone, nil
+}
+
+// applyUpdateToSales returns the FlashSales with the items, time-window, and stores
+// from the update applied to them. An error is returned if the update makes a
+// FlashSale invalid.
+func applyUpdateToSales(
+	sales map[objectid.ObjectID]*FlashSale,
+	update map[string]interface{},
+) ([]*FlashSale, error) {
+	updatedSales := make([]*FlashSale, 0)
+	for _, sale := range sales {
+		updatedSale, err := applySaleItemsUpdate(*sale, update)
+		if err == nil {
+			err = validateSaleWindow(updatedSale)
+		}
+		if err == nil {
+			err = validateSaleStores(updatedSale)
+		}
+		if err != nil {
+			return nil, err
+		}
+		updatedSales = append(updatedSales, updatedSale)
+	}
+	return updatedSales, nil
+}
+
+// findUpdateConflicts returns the FlashSaleIDs of FlashSales which conflict with
+// any of the updated FlashSales. The updated FlashSales are checked against each
+// other, instead of their stored versions which are replaced by the update.
+func findUpdateConflicts(
+	collection *mongo.Collection,
+	tx *txOptions,
+	updatedSales []*FlashSale,
+) ([]string, error) {
+	isUpdated := map[string]bool{}
+	for _, sale := range updatedSales {
+		isUpdated[sale.FlashSaleID.String()] = true
+	}
+
+	conflictIDs := make([]string, 0)
+	isConflict := map[string]bool{}
+	addConflict := func(id string) {
+		if !isConflict[id] {
+			isConflict[id] = true
+			conflictIDs = append(conflictIDs, id)
+		}
+	}
+	for i, sale := range updatedSales {
+		saleConflictIDs, err := findConflictingSales(collection, sale, tx.find()...)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range saleConflictIDs {
+			if !isUpdated[id] {
+				addConflict(id)
+			}
+		}
+		for _, otherSale := range updatedSales[i+1:] {
+			if salesOverlap(sale, otherSale) {
+				addConflict(sale.FlashSaleID.String())
+				addConflict(otherSale.FlashSaleID.String())
+			}
+		}
+	}
+	return conflictIDs, nil
+}
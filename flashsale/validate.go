@@ -0,0 +1,54 @@
+package flashsale
+
+import (
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// ValidateFlashSale checks that the FlashSale has the fields required for creating
+// it, and that the field-values are valid. A blank Status is treated as StatusActive.
+func ValidateFlashSale(s *FlashSale) error {
+	if s.FlashSaleID == (uuuid.UUID{}) {
+		return errors.New("missing FlashSaleID")
+	}
+	if len(s.Items) == 0 {
+		return errors.New("missing FlashSaleItems")
+	}
+	if s.Timestamp == 0 {
+		return errors.New("missing Timestamp")
+	}
+
+	for i, item := range s.Items {
+		if item.ItemID == (uuuid.UUID{}) {
+			return errors.Errorf("missing ItemID in item %d", i)
+		}
+		if item.Weight <= 0 {
+			return errors.Errorf("Weight must be greater than 0 in item %d", i)
+		}
+		if item.Price < 0 {
+			return errors.Errorf("Price cannot be negative in item %d", i)
+		}
+		if item.Discount < 0 || item.Discount >= 100 {
+			return errors.Errorf("Discount must be in range [0, 100) in item %d", i)
+		}
+	}
+
+	err := validateSaleWindow(s)
+	if err != nil {
+		return err
+	}
+	err = validateSaleStores(s)
+	if err != nil {
+		return err
+	}
+	if s.TimeZone != "" {
+		_, err = loadTimeZone(s.TimeZone)
+		if err != nil {
+			return err
+		}
+	}
+	if s.Status != "" && s.Status != StatusActive && s.Status != StatusDraft {
+		return errors.Errorf("invalid Status: %s", s.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/pkg/errors"
+)
+
+// runCommand runs the CLI subcommand with provided args.
+func runCommand(cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "export":
+		return runExport(cfg, args)
+	case "import":
+		return runImport(cfg, args)
+	case "rebuild":
+		return runRebuild(cfg, args)
+	case "handlers":
+		return runHandlers()
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	default:
+		printUsage()
+		return errors.Errorf("unknown command: %s", name)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s [command] [flags]
+
+Runs the FlashSale Aggregate service when no command is provided.
+
+Commands:
+  export    Export FlashSales to CSV or JSON Lines
+  import    Create FlashSales from a CSV file
+  rebuild   Rebuild the aggregate-collection by replaying the event-store
+  handlers  List the EventAction and ServiceAction pairs with registered handlers
+  help      Show this help
+
+Run "%s [command] -h" for the flags of a command.
+`, os.Args[0], os.Args[0])
+}
+
+// runHandlers prints the EventAction and ServiceAction of registered handlers.
+func runHandlers() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "EVENT-ACTION\tSERVICE-ACTION")
+	for _, key := range flashsale.DefaultRegistry().List() {
+		serviceAction := key.ServiceAction
+		if serviceAction == "" {
+			serviceAction = "(none)"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key.EventAction, serviceAction)
+	}
+	return w.Flush()
+}
+
+// parseTime parses a time provided either as Unix-seconds or in RFC3339 format.
+// A blank value is returned as 0.
+func parseTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return unix, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		err = errors.Errorf("time must be Unix-seconds or RFC3339: %s", value)
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/pkg/errors"
+)
+
+// exportSaleColumns are the FlashSale-fields included in each CSV-row.
+var exportSaleColumns = []string{
+	"flashSaleID", "status", "startTime", "endTime", "timestamp", "region", "storeIDs",
+}
+
+// exportItemColumns are the SoldItem-fields included in each CSV-row.
+var exportItemColumns = []string{
+	"itemID", "upc", "sku", "lot", "weight", "price", "discount",
+}
+
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "jsonl", `output format: "csv" or "jsonl"`)
+	outPath := flags.String("out", "", "path of output file (default stdout)")
+	from := flags.String("from", "", "export sales active after this time")
+	to := flags.String("to", "", "export sales active before this time")
+	batchSize := flags.Int64("batch-size", 500, "number of sales read per query")
+	filter := &flashsale.SaleFilter{}
+	flags.StringVar(&filter.Status, "status", "", "export sales with this status")
+	flags.StringVar(&filter.ItemID, "item", "", "export sales containing this itemID")
+	flags.StringVar(&filter.SKU, "sku", "", "export sales containing this SKU")
+	flags.StringVar(&filter.UPC, "upc", "", "export sales containing this UPC")
+	flags.StringVar(&filter.Lot, "lot", "", "export sales containing this lot")
+	flags.StringVar(&filter.Region, "region", "", "export sales applying to this region")
+	flags.StringVar(&filter.StoreID, "store", "", "export sales applying to this store")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s export [flags]
+
+Times are Unix-seconds or RFC3339. CSV output has one row per sale-item,
+with the storeIDs separated by ";".
+
+Flags:
+`, os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *format != "csv" && *format != "jsonl" {
+		flags.Usage()
+		return errors.Errorf("export: invalid format: %s", *format)
+	}
+	var err error
+	filter.From, err = parseTime(*from)
+	if err != nil {
+		err = errors.Wrap(err, "export: Error parsing -from")
+		return err
+	}
+	filter.To, err = parseTime(*to)
+	if err != nil {
+		err = errors.Wrap(err, "export: Error parsing -to")
+		return err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "export")
+		return err
+	}
+	mc, err := loadMongoConfig(&cfg.Mongo)
+	if err != nil {
+		err = errors.Wrap(err, "export: Error in MongoConfig")
+		return err
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		out, err = os.Create(*outPath)
+		if err != nil {
+			err = errors.Wrap(err, "export: Error creating output file")
+			return err
+		}
+		defer out.Close()
+	}
+	writer := bufio.NewWriter(out)
+
+	stream := func(callback func(*flashsale.FlashSale) error) error {
+		return flashsale.StreamSales(mc.AggCollection, filter, *batchSize, callback)
+	}
+	if *format == "csv" {
+		err = exportCSV(writer, stream)
+	} else {
+		err = exportJSONL(writer, stream)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "export")
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		err = errors.Wrap(err, "export: Error writing output")
+		return err
+	}
+	return nil
+}
+
+// saleStream calls the callback for each exported FlashSale.
+type saleStream func(callback func(*flashsale.FlashSale) error) error
+
+// exportJSONL writes each FlashSale as a JSON-line.
+func exportJSONL(w io.Writer, stream saleStream) error {
+	return stream(func(flashSale *flashsale.FlashSale) error {
+		marshalSale, err := json.Marshal(flashSale)
+		if err != nil {
+			err = errors.Wrap(err, "Error marshalling FlashSale")
+			return err
+		}
+		_, err = w.Write(append(marshalSale, '\n'))
+		return err
+	})
+}
+
+// exportCSV writes a CSV-row for each item of each FlashSale.
+// The FlashSales are converted using their JSON-marshalling,
+// so the CSV-values are formatted same as the JSON-values.
+func exportCSV(w io.Writer, stream saleStream) error {
+	csvWriter := csv.NewWriter(w)
+	header := append(
+		append([]string{}, exportSaleColumns...),
+		exportItemColumns...,
+	)
+	err := csvWriter.Write(header)
+	if err != nil {
+		err = errors.Wrap(err, "Error writing CSV header")
+		return err
+	}
+
+	err = stream(func(flashSale *flashsale.FlashSale) error {
+		marshalSale, err := json.Marshal(flashSale)
+		if err != nil {
+			err = errors.Wrap(err, "Error marshalling FlashSale")
+			return err
+		}
+		sale := map[string]interface{}{}
+		err = json.Unmarshal(marshalSale, &sale)
+		if err != nil {
+			err = errors.Wrap(err, "Error unmarshalling FlashSale")
+			return err
+		}
+
+		saleValues := make([]string, 0)
+		for _, col := range exportSaleColumns {
+			saleValues = append(saleValues, csvValue(sale[col]))
+		}
+		items, _ := sale["items"].([]interface{})
+		for _, i := range items {
+			item, _ := i.(map[string]interface{})
+			row := append([]string{}, saleValues...)
+			for _, col := range exportItemColumns {
+				row = append(row, csvValue(item[col]))
+			}
+			err = csvWriter.Write(row)
+			if err != nil {
+				err = errors.Wrap(err, "Error writing CSV row")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// csvValue formats a JSON-value as CSV-value.
+func csvValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []interface{}:
+		values := make([]string, 0)
+		for _, v := range value {
+			values = append(values, csvValue(v))
+		}
+		return strings.Join(values, ";")
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
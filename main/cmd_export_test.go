@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Export", func() {
+	It("should format JSON-values as CSV-values", func() {
+		cases := []struct {
+			value    interface{}
+			expected string
+		}{
+			{nil, ""},
+			{"west", "west"},
+			{float64(1541250000), "1541250000"},
+			{1.5, "1.5"},
+			{true, "true"},
+			{[]interface{}{}, ""},
+			{[]interface{}{"store-1", "store-2"}, "store-1;store-2"},
+			{[]interface{}{float64(1), nil}, "1;"},
+		}
+		for _, c := range cases {
+			Expect(csvValue(c.value)).To(Equal(c.expected), "value: %v", c.value)
+		}
+	})
+
+	It("should write a CSV-row for each item of FlashSale", func() {
+		flashSaleID, err := uuuid.FromString("6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e")
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.FromString("2c6f1b8e-4a3d-4e5f-9b7a-1d0c8e6f4a21")
+		Expect(err).ToNot(HaveOccurred())
+		flashSale := &flashsale.FlashSale{
+			FlashSaleID: flashSaleID,
+			Items: []flashsale.SoldItem{
+				flashsale.SoldItem{
+					ItemID:   itemID,
+					UPC:      "012345678905",
+					SKU:      "sku-1",
+					Lot:      "lot-1",
+					Weight:   10,
+					Price:    1.5,
+					Discount: 40,
+				},
+				flashsale.SoldItem{
+					ItemID:   itemID,
+					UPC:      "012345678905",
+					SKU:      "sku-1",
+					Lot:      "lot-2",
+					Weight:   2.5,
+					Price:    2,
+					Discount: 20,
+				},
+			},
+			StartTime: 1541250000,
+			EndTime:   1541260800,
+			Status:    flashsale.StatusActive,
+			Timestamp: 1541240000,
+			Region:    "west",
+			StoreIDs:  []string{"store-1", "store-2"},
+		}
+		stream := func(callback func(*flashsale.FlashSale) error) error {
+			return callback(flashSale)
+		}
+
+		out := &bytes.Buffer{}
+		Expect(exportCSV(out, stream)).To(Succeed())
+		records, err := csv.NewReader(out).ReadAll()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(Equal([][]string{
+			{
+				"flashSaleID", "status", "startTime", "endTime", "timestamp",
+				"region", "storeIDs",
+				"itemID", "upc", "sku", "lot", "weight", "price", "discount",
+			},
+			{
+				flashSaleID.String(), "active", "1541250000", "1541260800", "1541240000",
+				"west", "store-1;store-2",
+				itemID.String(), "012345678905", "sku-1", "lot-1", "10", "1.5", "40",
+			},
+			{
+				flashSaleID.String(), "active", "1541250000", "1541260800", "1541240000",
+				"west", "store-1;store-2",
+				itemID.String(), "012345678905", "sku-1", "lot-2", "2.5", "2", "20",
+			},
+		}))
+	})
+})
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// autoFlashSaleID is the flashSaleID-column value for generating the FlashSaleID.
+// Rows with this value and the same start/end are imported as a single FlashSale.
+const autoFlashSaleID = "auto"
+
+var importColumns = []string{
+	"flashsaleid", "itemid", "upc", "sku", "lot", "weight", "price", "start", "end",
+	"region", "storeids", "timezone",
+}
+
+// importRow is a parsed CSV-row.
+type importRow struct {
+	line        int
+	flashSaleID string
+	item        flashsale.SoldItem
+	startTime   int64
+	endTime     int64
+	region      string
+	storeIDs    []string
+	timeZone    string
+	err         error
+}
+
+// importSale is a FlashSale built from one or more CSV-rows.
+type importSale struct {
+	flashSale *flashsale.FlashSale
+	rows      []*importRow
+	err       error
+	// publishErr is set if the Event for FlashSale was not acknowledged.
+	publishErr error
+}
+
+func runImport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	filePath := flags.String("file", "", "path of CSV file to import (required)")
+	dryRun := flags.Bool(
+		"dry-run", false, "validate rows and print report without publishing",
+	)
+	userUUIDStr := flags.String("user", "", "UserUUID to set on the created events")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s import -file <path> [flags]
+
+CSV columns (with header): %s
+Use "%s" as flashSaleID to generate it; such rows with the same start/end,
+region, storeIDs and timeZone form a single FlashSale. Times are Unix-seconds
+or RFC3339, and storeIDs are separated by ";".
+
+Flags:
+`, os.Args[0], strings.Join(importColumns, ","), autoFlashSaleID)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *filePath == "" {
+		flags.Usage()
+		return errors.New("import: -file is required")
+	}
+	// Config is only used for publishing the FlashSales
+	if !*dryRun {
+		err := cfg.Validate()
+		if err != nil {
+			err = errors.Wrap(err, "import")
+			return err
+		}
+	}
+	userUUID := uuuid.UUID{}
+	if *userUUIDStr != "" {
+		var err error
+		userUUID, err = uuuid.FromString(*userUUIDStr)
+		if err != nil {
+			err = errors.Wrap(err, "import: Error parsing -user")
+			return err
+		}
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		err = errors.Wrap(err, "import: Error opening CSV file")
+		return err
+	}
+	defer file.Close()
+
+	rows, err := parseImportCSV(file)
+	if err != nil {
+		err = errors.Wrap(err, "import")
+		return err
+	}
+	sales := buildImportSales(rows, time.Now())
+
+	if !*dryRun {
+		producer, err := newImportProducer(&cfg.Kafka)
+		if err != nil {
+			err = errors.Wrap(err, "import")
+			return err
+		}
+		publishImportSales(producer, cfg.Kafka.ProducerEventTopic, sales, userUUID)
+		err = producer.Close()
+		if err != nil {
+			err = errors.Wrap(err, "import: Error closing producer")
+			return err
+		}
+	}
+
+	failCount := printImportReport(os.Stdout, sales, *dryRun)
+	if failCount > 0 {
+		err = errors.Errorf("%d of %d rows were not imported", failCount, len(rows))
+		err = errors.Wrap(err, "import")
+		return err
+	}
+	return nil
+}
+
+// parseImportCSV parses the CSV-rows. Errors in individual rows are
+// set on the row, and only CSV-format errors are returned.
+func parseImportCSV(r io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		err = errors.Wrap(err, "Error reading CSV header")
+		return nil, err
+	}
+	colIndex := map[string]int{}
+	for i, col := range header {
+		colIndex[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range []string{"flashsaleid", "itemid", "weight"} {
+		if _, exists := colIndex[col]; !exists {
+			return nil, errors.Errorf("missing required CSV column: %s", col)
+		}
+	}
+
+	rows := make([]*importRow, 0)
+	// Line 1 is the header
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			err = errors.Wrapf(err, "Error reading CSV line %d", line)
+			return nil, err
+		}
+
+		value := func(col string) string {
+			i, exists := colIndex[col]
+			if !exists || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		rows = append(rows, parseImportRow(line, value))
+	}
+	return rows, nil
+}
+
+func parseImportRow(line int, value func(col string) string) *importRow {
+	row := &importRow{
+		line:        line,
+		flashSaleID: value("flashsaleid"),
+		item: flashsale.SoldItem{
+			UPC: value("upc"),
+			SKU: value("sku"),
+			Lot: value("lot"),
+		},
+	}
+
+	var err error
+	if row.flashSaleID == "" {
+		row.err = errors.New("missing flashSaleID")
+		return row
+	}
+	if row.flashSaleID != autoFlashSaleID {
+		_, err = uuuid.FromString(row.flashSaleID)
+		if err != nil {
+			row.err = errors.Wrap(err, "invalid flashSaleID")
+			return row
+		}
+	}
+
+	row.item.ItemID, err = uuuid.FromString(value("itemid"))
+	if err != nil {
+		row.err = errors.Wrap(err, "invalid itemID")
+		return row
+	}
+	row.item.Weight, err = strconv.ParseFloat(value("weight"), 64)
+	if err != nil {
+		row.err = errors.Wrap(err, "invalid weight")
+		return row
+	}
+	if value("price") != "" {
+		row.item.Price, err = strconv.ParseFloat(value("price"), 64)
+		if err != nil {
+			row.err = errors.Wrap(err, "invalid price")
+			return row
+		}
+	}
+
+	row.startTime, err = parseTime(value("start"))
+	if err != nil {
+		row.err = errors.Wrap(err, "invalid start")
+		return row
+	}
+	row.endTime, err = parseTime(value("end"))
+	if err != nil {
+		row.err = errors.Wrap(err, "invalid end")
+		return row
+	}
+
+	row.region = value("region")
+	row.storeIDs = []string{}
+	for _, storeID := range strings.Split(value("storeids"), ";") {
+		storeID = strings.TrimSpace(storeID)
+		if storeID != "" {
+			row.storeIDs = append(row.storeIDs, storeID)
+		}
+	}
+	row.timeZone = value("timezone")
+	return row
+}
+
+// saleKey returns the values which must be same for all rows of a FlashSale.
+func (r *importRow) saleKey() string {
+	return fmt.Sprintf(
+		"%d|%d|%s|%s|%s",
+		r.startTime, r.endTime, r.region, strings.Join(r.storeIDs, ";"), r.timeZone,
+	)
+}
+
+// buildImportSales groups the rows into FlashSales and validates them.
+// A FlashSale is invalid if any of its rows is invalid.
+func buildImportSales(rows []*importRow, now time.Time) []*importSale {
+	sales := make([]*importSale, 0)
+	salesByKey := map[string]*importSale{}
+
+	for _, row := range rows {
+		key := row.flashSaleID
+		if key == autoFlashSaleID {
+			key = autoFlashSaleID + "|" + row.saleKey()
+		}
+		sale, exists := salesByKey[key]
+		if !exists {
+			sale = &importSale{
+				flashSale: &flashsale.FlashSale{
+					Items:     []flashsale.SoldItem{},
+					StartTime: row.startTime,
+					EndTime:   row.endTime,
+					Region:    row.region,
+					StoreIDs:  row.storeIDs,
+					TimeZone:  row.timeZone,
+					Timestamp: now.Unix(),
+				},
+			}
+			salesByKey[key] = sale
+			sales = append(sales, sale)
+		}
+		sale.rows = append(sale.rows, row)
+
+		if row.err != nil {
+			sale.err = errors.Errorf("line %d is invalid", row.line)
+			continue
+		}
+		if row.saleKey() != sale.rows[0].saleKey() {
+			row.err = errors.New(
+				"start/end, region, storeIDs or timeZone differs " +
+					"from other rows of the flashSale",
+			)
+			sale.err = errors.Errorf("line %d is invalid", row.line)
+			continue
+		}
+		sale.flashSale.Items = append(sale.flashSale.Items, row.item)
+	}
+
+	for _, sale := range sales {
+		if sale.err != nil {
+			continue
+		}
+		var err error
+		flashSaleID := sale.rows[0].flashSaleID
+		if flashSaleID == autoFlashSaleID {
+			sale.flashSale.FlashSaleID, err = uuuid.NewV4()
+		} else {
+			sale.flashSale.FlashSaleID, err = uuuid.FromString(flashSaleID)
+		}
+		if err == nil {
+			err = flashsale.ValidateFlashSale(sale.flashSale)
+		}
+		sale.err = err
+	}
+	return sales
+}
+
+// newImportProducer creates a sync-producer for publishing the Events, so each
+// Event is acknowledged before it is reported as published.
+func newImportProducer(k *config.Kafka) (sarama.SyncProducer, error) {
+	if len(k.Brokers) == 0 || k.ProducerEventTopic == "" {
+		return nil, errors.New(
+			"KAFKA_BROKERS and KAFKA_PRODUCER_EVENT_TOPIC are required for publishing",
+		)
+	}
+	saramaConfig, err := k.SaramaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating producer config")
+		return nil, err
+	}
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Return.Errors = true
+	producer, err := sarama.NewSyncProducer(k.Brokers, saramaConfig)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating producer")
+		return nil, err
+	}
+	return producer, nil
+}
+
+// publishImportSales produces "insert" Events for the valid FlashSales. The
+// FlashSales whose Events are not acknowledged are marked with publishErr.
+func publishImportSales(
+	sender flashsale.MessageSender,
+	topic string,
+	sales []*importSale,
+	userUUID uuuid.UUID,
+) {
+	for _, sale := range sales {
+		if sale.err != nil {
+			continue
+		}
+		event, err := newInsertEvent(sale.flashSale, userUUID)
+		if err != nil {
+			sale.publishErr = errors.Wrap(err, "Error creating Event")
+			continue
+		}
+		marshalEvent, err := json.Marshal(event)
+		if err != nil {
+			sale.publishErr = errors.Wrap(err, "Error marshalling Event")
+			continue
+		}
+		_, _, err = sender.SendMessage(&sarama.ProducerMessage{
+			Topic: topic,
+			Value: sarama.ByteEncoder(marshalEvent),
+		})
+		if err != nil {
+			sale.publishErr = errors.Wrap(err, "Error publishing Event")
+			log.Println(sale.publishErr)
+		}
+	}
+}
+
+// newInsertEvent creates a "flashSaleCreated" Event for the FlashSale.
+func newInsertEvent(
+	flashSale *flashsale.FlashSale,
+	userUUID uuuid.UUID,
+) (*model.Event, error) {
+	data, err := json.Marshal(flashSale)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling FlashSale")
+		return nil, err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return nil, err
+	}
+	cid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+
+	now := time.Now()
+	return &model.Event{
+		AggregateID:   flashsale.AggregateID,
+		CorrelationID: cid,
+		Data:          data,
+		EventAction:   "insert",
+		NanoTime:      now.UnixNano(),
+		ServiceAction: "flashSaleCreated",
+		UserUUID:      userUUID,
+		UUID:          uuid,
+		Version:       0,
+		YearBucket:    int16(now.Year()),
+	}, nil
+}
+
+// printImportReport prints the result of each row, and returns the number of failed rows.
+func printImportReport(w io.Writer, sales []*importSale, dryRun bool) int {
+	okStatus := "PUBLISHED"
+	if dryRun {
+		okStatus = "VALID"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tFLASHSALE-ID\tITEM-ID\tSTATUS\tERROR")
+
+	failCount := 0
+	for _, sale := range sales {
+		flashSaleID := "-"
+		if sale.flashSale.FlashSaleID != (uuuid.UUID{}) {
+			flashSaleID = sale.flashSale.FlashSaleID.String()
+		}
+		for _, row := range sale.rows {
+			status := okStatus
+			errStr := ""
+			if row.err != nil {
+				status = "INVALID"
+				errStr = row.err.Error()
+			} else if sale.err != nil {
+				status = "SKIPPED"
+				errStr = sale.err.Error()
+			} else if sale.publishErr != nil {
+				status = "FAILED"
+				errStr = sale.publishErr.Error()
+			}
+			if status != okStatus {
+				failCount++
+			}
+
+			itemID := "-"
+			if row.item.ItemID != (uuuid.UUID{}) {
+				itemID = row.item.ItemID.String()
+			}
+			fmt.Fprintf(
+				tw, "%d\t%s\t%s\t%s\t%s\n",
+				row.line, flashSaleID, itemID, status, errStr,
+			)
+		}
+	}
+	tw.Flush()
+	return failCount
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// mockSender fails sending the message with index failAt (starting at 1).
+type mockSender struct {
+	failAt int
+	calls  int
+	sent   int
+}
+
+func (m *mockSender) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	m.calls++
+	if m.calls == m.failAt {
+		return 0, 0, errors.New("not acknowledged")
+	}
+	m.sent++
+	return 0, int64(m.sent), nil
+}
+
+var _ = Describe("Import", func() {
+	const itemID = "2c6f1b8e-4a3d-4e5f-9b7a-1d0c8e6f4a21"
+
+	parse := func(lines ...string) []*importRow {
+		rows, err := parseImportCSV(strings.NewReader(strings.Join(lines, "\n")))
+		Expect(err).ToNot(HaveOccurred())
+		return rows
+	}
+
+	Describe("parseImportCSV", func() {
+		It("should parse the stores and time zone of rows", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,start,end,region,storeIDs,timeZone",
+				"auto,"+itemID+",10,1541250000,1541260800,west,store-1; store-2,"+
+					"America/Toronto",
+			)
+			Expect(rows).To(HaveLen(1))
+			Expect(rows[0].err).ToNot(HaveOccurred())
+			Expect(rows[0].line).To(Equal(2))
+			Expect(rows[0].item.Weight).To(Equal(float64(10)))
+			Expect(rows[0].startTime).To(Equal(int64(1541250000)))
+			Expect(rows[0].region).To(Equal("west"))
+			Expect(rows[0].storeIDs).To(Equal([]string{"store-1", "store-2"}))
+			Expect(rows[0].timeZone).To(Equal("America/Toronto"))
+		})
+
+		It("should set errors on invalid rows", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,start",
+				"auto,invalid,10,",
+				"auto,"+itemID+",ten,",
+				"auto,"+itemID+",10,yesterday",
+				","+itemID+",10,",
+			)
+			Expect(rows).To(HaveLen(4))
+			for _, row := range rows {
+				Expect(row.err).To(HaveOccurred())
+			}
+		})
+
+		It("should return error for missing required columns", func() {
+			_, err := parseImportCSV(strings.NewReader("flashSaleID,itemID\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("buildImportSales", func() {
+		It("should group auto rows with same window and stores", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,start,end,storeIDs",
+				"auto,"+itemID+",10,100,200,store-1",
+				"auto,"+itemID+",20,100,200,store-1",
+				"auto,"+itemID+",30,100,200,store-2",
+			)
+			sales := buildImportSales(rows, time.Now())
+			Expect(sales).To(HaveLen(2))
+			Expect(sales[0].err).ToNot(HaveOccurred())
+			Expect(sales[0].flashSale.Items).To(HaveLen(2))
+			Expect(sales[0].flashSale.StoreIDs).To(Equal([]string{"store-1"}))
+			Expect(sales[1].flashSale.StoreIDs).To(Equal([]string{"store-2"}))
+		})
+
+		It("should invalidate FlashSales whose rows differ in stores", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,region",
+				"6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e,"+itemID+",10,west",
+				"6a6d5d3e-6a4c-4a3c-9a5e-2c1f0c4d7b8e,"+itemID+",10,east",
+			)
+			sales := buildImportSales(rows, time.Now())
+			Expect(sales).To(HaveLen(1))
+			Expect(sales[0].err).To(HaveOccurred())
+			Expect(rows[1].err).To(HaveOccurred())
+		})
+
+		It("should validate the FlashSales", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,start,end,timeZone",
+				"auto,"+itemID+",10,200,100,",
+				"auto,"+itemID+",10,100,200,Mars/Olympus_Mons",
+			)
+			sales := buildImportSales(rows, time.Now())
+			Expect(sales).To(HaveLen(2))
+			Expect(sales[0].err).To(HaveOccurred())
+			Expect(sales[1].err).To(HaveOccurred())
+		})
+	})
+
+	Describe("publishImportSales", func() {
+		It("should only report acknowledged FlashSales as published", func() {
+			rows := parse(
+				"flashSaleID,itemID,weight,region",
+				"auto,"+itemID+",10,west",
+				"auto,"+itemID+",10,east",
+			)
+			sales := buildImportSales(rows, time.Now())
+			Expect(sales).To(HaveLen(2))
+
+			sender := &mockSender{
+				failAt: 2,
+			}
+			publishImportSales(sender, "events", sales, uuuid.UUID{})
+			Expect(sender.sent).To(Equal(1))
+			Expect(sales[0].publishErr).ToNot(HaveOccurred())
+			Expect(sales[1].publishErr).To(HaveOccurred())
+
+			report := &bytes.Buffer{}
+			Expect(printImportReport(report, sales, false)).To(Equal(1))
+			Expect(report.String()).To(ContainSubstring("PUBLISHED"))
+			Expect(report.String()).To(ContainSubstring("FAILED"))
+		})
+	})
+})
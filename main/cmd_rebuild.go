@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/pkg/errors"
+)
+
+// esQueryHandler consumes the EventStore-query response with matching CorrelationID.
+type esQueryHandler struct {
+	correlationID uuuid.UUID
+	readyOnce     sync.Once
+	ready         chan struct{}
+	result        chan *model.Document
+}
+
+func (h *esQueryHandler) Setup(sarama.ConsumerGroupSession) error {
+	h.readyOnce.Do(func() {
+		close(h.ready)
+	})
+	return nil
+}
+
+func (*esQueryHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *esQueryHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		session.MarkMessage(msg, "")
+
+		doc := &model.Document{}
+		err := json.Unmarshal(msg.Value, doc)
+		if err != nil {
+			err = errors.Wrap(err, "Error unmarshalling EventStore-query response")
+			log.Println(err)
+			continue
+		}
+		if doc.CorrelationID == h.correlationID {
+			h.result <- doc
+			return nil
+		}
+	}
+	return nil
+}
+
+func runRebuild(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	fromYear := flags.Int(
+		"from-year", 0, "first year-bucket of events to replay (required)",
+	)
+	toYear := flags.Int(
+		"to-year", time.Now().Year(), "last year-bucket of events to replay",
+	)
+	timeoutSec := flags.Int(
+		"timeout", 60, "seconds to wait for events from the event-store",
+	)
+	noSwap := flags.Bool(
+		"no-swap", false, "keep the rebuilt shadow-collection without swapping it in",
+	)
+	force := flags.Bool(
+		"force", false, "swap in the rebuilt shadow-collection even if events failed",
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s rebuild [flags]
+
+Rebuilds the aggregate-collection by replaying all events of the FlashSale
+Aggregate in the year-buckets from-year through to-year from the event-store
+into a shadow-collection, which then replaces the aggregate-collection. The
+shadow-collection is not swapped in if any events failed to replay, unless
+-force is given. The service should be stopped while rebuilding.
+
+Flags:
+`, os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *fromYear <= 0 {
+		return errors.New("rebuild: -from-year is required")
+	}
+	if *fromYear > *toYear {
+		return errors.New("rebuild: -from-year cannot be after -to-year")
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "rebuild")
+		return err
+	}
+	mc, err := loadMongoConfig(&cfg.Mongo)
+	if err != nil {
+		err = errors.Wrap(err, "rebuild: Error in MongoConfig")
+		return err
+	}
+
+	timeout := time.Duration(*timeoutSec) * time.Second
+	events := []model.Event{}
+	for year := *fromYear; year <= *toYear; year++ {
+		yearEvents, err := queryAggregateEvents(&cfg.Kafka, int16(year), timeout)
+		if err != nil {
+			err = errors.Wrapf(err, "rebuild: year-bucket %d", year)
+			return err
+		}
+		log.Printf("Received %d events for year-bucket %d", len(yearEvents), year)
+		events = append(events, yearEvents...)
+	}
+	log.Printf("Received %d events from event-store", len(events))
+
+	aggName := cfg.Mongo.AggCollection
+	shadowName := aggName + "_rebuild"
+	shadowCollection, err := createMongoCollection(
+		mc.Connection, mc.MetaDatabaseName, shadowName, cfg.Mongo.SchemaValidation,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "rebuild: Error creating shadow-collection")
+		return err
+	}
+	_, err = shadowCollection.DeleteMany(map[string]interface{}{})
+	if err != nil {
+		err = errors.Wrap(err, "rebuild: Error clearing shadow-collection")
+		return err
+	}
+
+	replayContext := flashsale.NewReplayContext(cfg)
+	lastVersion, failCount := replayEvents(replayContext, shadowCollection, events)
+	log.Printf(
+		"Replayed %d events into %s, %d events failed",
+		len(events), shadowName, failCount,
+	)
+	printEmissions(replayContext.Emitted())
+
+	if *noSwap {
+		log.Printf("Skipping swap, rebuilt collection is available as %s", shadowName)
+		return nil
+	}
+	if failCount > 0 && !*force {
+		return errors.Errorf(
+			"rebuild: %d events failed to replay, not swapping %s into %s "+
+				"(use -force to swap anyway)",
+			failCount, shadowName, aggName,
+		)
+	}
+	err = swapCollection(mc.Connection, mc.MetaDatabaseName, shadowName, aggName)
+	if err != nil {
+		err = errors.Wrap(err, "rebuild")
+		return err
+	}
+	err = updateAggregateVersion(mc, lastVersion)
+	if err != nil {
+		err = errors.Wrap(err, "rebuild")
+		return err
+	}
+	log.Printf("Swapped %s into %s at version %d", shadowName, aggName, lastVersion)
+	return nil
+}
+
+// queryAggregateEvents requests all events of FlashSale Aggregate from the
+// event-store using the same topics as the EventStore-query of EventPoll.
+func queryAggregateEvents(
+	k *config.Kafka, yearBucket int16, timeout time.Duration,
+) ([]model.Event, error) {
+	cEventQueryTopic := fmt.Sprintf(
+		"%s.%d", k.ConsumerEventQueryTopic, flashsale.AggregateID,
+	)
+
+	cid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// A unique group is used so the query-responses for the service aren't consumed
+	groupName := fmt.Sprintf(
+		"%s.rebuild.%s", k.ConsumerEventQueryGroup, cid,
+	)
+	consConfig, err := k.SaramaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating EventStore-query consumer config")
+		return nil, err
+	}
+	consumer, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+		KafkaBrokers: k.Brokers,
+		GroupName:    groupName,
+		Topics:       []string{cEventQueryTopic},
+		SaramaConfig: consConfig,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating EventStore-query consumer")
+		return nil, err
+	}
+	defer consumer.Close()
+
+	handler := &esQueryHandler{
+		correlationID: cid,
+		ready:         make(chan struct{}),
+		result:        make(chan *model.Document, 1),
+	}
+	go func() {
+		err := consumer.Consume(ctx, handler)
+		if err != nil {
+			err = errors.Wrap(err, "Error consuming EventStore-query response")
+			log.Println(err)
+		}
+	}()
+
+	// Query is produced only after consumer is ready, so the response isn't missed
+	select {
+	case <-handler.ready:
+	case <-ctx.Done():
+		return nil, errors.New("timed out waiting for EventStore-query consumer")
+	}
+
+	query := &model.EventStoreQuery{
+		AggregateID:      flashsale.AggregateID,
+		AggregateVersion: 0,
+		CorrelationID:    cid,
+		UUID:             uuid,
+		YearBucket:       yearBucket,
+	}
+	marshalQuery, err := json.Marshal(query)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling EventStore-query")
+		return nil, err
+	}
+	prodConfig, err := k.SaramaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating EventStore-query producer config")
+		return nil, err
+	}
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: k.Brokers,
+		SaramaConfig: prodConfig,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating EventStore-query producer")
+		return nil, err
+	}
+	producer.Input() <- kafka.CreateMessage(k.ProducerEventQueryTopic, marshalQuery)
+	err = producer.Close()
+	if err != nil {
+		err = errors.Wrap(err, "Error producing EventStore-query")
+		return nil, err
+	}
+
+	var doc *model.Document
+	select {
+	case doc = <-handler.result:
+	case <-ctx.Done():
+		return nil, errors.New("timed out waiting for EventStore-query response")
+	}
+	if doc.Error != "" {
+		return nil, errors.Errorf("EventStore-query error: %s", doc.Error)
+	}
+
+	events := []model.Event{}
+	err = json.Unmarshal(doc.Result, &events)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling EventStore-query events")
+		return nil, err
+	}
+	return events, nil
+}
+
+// replayEvents replays the events in order of their versions, and returns the
+// last replayed version and the number of events which failed to replay.
+func replayEvents(
+	replayContext *flashsale.ExecContext,
+	collection *mongo.Collection,
+	events []model.Event,
+) (int64, int) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Version == events[j].Version {
+			return events[i].NanoTime < events[j].NanoTime
+		}
+		return events[i].Version < events[j].Version
+	})
+
+	lastVersion := int64(0)
+	failCount := 0
+	for i := range events {
+		event := &events[i]
+		err := replayContext.Apply(collection, event)
+		if err != nil {
+			err = errors.Wrapf(err, "Error replaying event %s", event.UUID)
+			log.Println(err)
+			failCount++
+		}
+		if event.Version > lastVersion {
+			lastVersion = event.Version
+		}
+	}
+	return lastVersion, failCount
+}
+
+// printEmissions summarizes the Events and Documents suppressed during replay.
+func printEmissions(emissions []flashsale.Emission) {
+	eventCount := map[string]int{}
+	docCount := 0
+	for _, e := range emissions {
+		if e.Event != nil {
+			key := fmt.Sprintf("%s/%s", e.Event.EventAction, e.Event.ServiceAction)
+			eventCount[key]++
+		}
+		if e.Document != nil {
+			docCount++
+		}
+	}
+	log.Printf("Suppressed %d response-Documents during replay", docCount)
+	for key, count := range eventCount {
+		log.Printf("Suppressed %d %s Events during replay", count, key)
+	}
+}
+
+// swapCollection atomically replaces the target-collection with the source-collection.
+func swapCollection(
+	conn *mongo.ConnectionConfig, db string, source string, target string,
+) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(conn.Timeout)*time.Millisecond,
+	)
+	defer cancel()
+
+	// Command-document needs ordered keys, hence not using a map
+	cmd := bson.NewDocument(
+		bson.EC.String("renameCollection", db+"."+source),
+		bson.EC.String("to", db+"."+target),
+		bson.EC.Boolean("dropTarget", true),
+	)
+	_, err := conn.Client.Database("admin").RunCommand(ctx, cmd)
+	if err != nil {
+		err = errors.Wrapf(err, "Error renaming collection %s to %s", source, target)
+		return err
+	}
+	return nil
+}
+
+// updateAggregateVersion sets the aggregate-version in meta-collection, so
+// EventPoll only requests the events after the replayed version.
+func updateAggregateVersion(mc *poll.MongoConfig, version int64) error {
+	c := &mongo.Collection{
+		Connection:   mc.Connection,
+		Database:     mc.MetaDatabaseName,
+		Name:         mc.MetaCollectionName,
+		SchemaStruct: &model.EventMeta{},
+	}
+	metaCollection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating meta-collection")
+		return err
+	}
+
+	_, err = metaCollection.UpdateMany(
+		map[string]interface{}{
+			"aggregateID": flashsale.AggregateID,
+		},
+		map[string]interface{}{
+			"aggregateVersion": version,
+		},
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error updating aggregate-version")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+func loadExpiryConfig(
+	cfg *config.Config, conn *mongo.ConnectionConfig, db string,
+) (*flashsale.ExpiryConfig, error) {
+	err := cfg.Expiry.Validate(&cfg.Mongo)
+	if err != nil {
+		return nil, err
+	}
+
+	curve, err := flashsale.ParseDiscountCurve(cfg.Expiry.DiscountCurve)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing EXPIRY_SALE_DISCOUNT_CURVE")
+		return nil, err
+	}
+
+	c := &mongo.Collection{
+		Connection:   conn,
+		Database:     db,
+		Name:         cfg.Mongo.InventoryCollection,
+		SchemaStruct: &flashsale.InventoryLot{},
+	}
+	invMongoCollection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating Inventory MongoCollection")
+		return nil, err
+	}
+
+	return &flashsale.ExpiryConfig{
+		AutoCreate:          cfg.Expiry.AutoCreate,
+		DaysBeforeExpiry:    cfg.Expiry.SaleDays,
+		DiscountCurve:       curve,
+		InventoryCollection: invMongoCollection,
+		Interval:            time.Duration(cfg.Expiry.IntervalSec) * time.Second,
+		TimeZone:            cfg.Expiry.TimeZone,
+	}, nil
+}
@@ -2,44 +2,49 @@ package main
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
 	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventspoll/poll"
 	"github.com/TerrexTech/go-kafkautils/kafka"
 )
 
-func loadKafkaConfig() (*poll.KafkaConfig, error) {
-	kafkaBrokers := *commonutil.ParseHosts(
-		os.Getenv("KAFKA_BROKERS"),
+func loadKafkaConfig(k *config.Kafka) (*poll.KafkaConfig, error) {
+	cEventTopic := fmt.Sprintf("%s.%d", k.ConsumerEventTopic, flashsale.AggregateID)
+	cEventQueryTopic := fmt.Sprintf(
+		"%s.%d", k.ConsumerEventQueryTopic, flashsale.AggregateID,
 	)
 
-	cEventGroup := os.Getenv("KAFKA_CONSUMER_EVENT_GROUP")
-	cEventQueryGroup := os.Getenv("KAFKA_CONSUMER_EVENT_QUERY_GROUP")
-	cEventTopic := os.Getenv("KAFKA_CONSUMER_EVENT_TOPIC")
-	cEventQueryTopic := os.Getenv("KAFKA_CONSUMER_EVENT_QUERY_TOPIC")
-	pEventQueryTopic := os.Getenv("KAFKA_PRODUCER_EVENT_QUERY_TOPIC")
-
-	cEventTopic = fmt.Sprintf("%s.%d", cEventTopic, flashsale.AggregateID)
-	cEventQueryTopic = fmt.Sprintf("%s.%d", cEventQueryTopic, flashsale.AggregateID)
+	// Each client gets its own sarama-config, since clients modify their configs
+	saramaConfigs := make([]*sarama.Config, 3)
+	for i := range saramaConfigs {
+		saramaConfig, err := k.SaramaConfig()
+		if err != nil {
+			return nil, err
+		}
+		saramaConfigs[i] = saramaConfig
+	}
 
 	kc := &poll.KafkaConfig{
 		EventCons: &kafka.ConsumerConfig{
-			KafkaBrokers: kafkaBrokers,
-			GroupName:    cEventGroup,
+			KafkaBrokers: k.Brokers,
+			GroupName:    k.ConsumerEventGroup,
 			Topics:       []string{cEventTopic},
+			SaramaConfig: saramaConfigs[0],
 		},
 		ESQueryResCons: &kafka.ConsumerConfig{
-			KafkaBrokers: kafkaBrokers,
-			GroupName:    cEventQueryGroup,
+			KafkaBrokers: k.Brokers,
+			GroupName:    k.ConsumerEventQueryGroup,
 			Topics:       []string{cEventQueryTopic},
+			SaramaConfig: saramaConfigs[1],
 		},
 
 		ESQueryReqProd: &kafka.ProducerConfig{
-			KafkaBrokers: kafkaBrokers,
+			KafkaBrokers: k.Brokers,
+			SaramaConfig: saramaConfigs[2],
 		},
-		ESQueryReqTopic: pEventQueryTopic,
+		ESQueryReqTopic: k.ProducerEventQueryTopic,
 	}
 
 	return kc, nil
@@ -1,66 +1,93 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"net/url"
+	"time"
 
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
 	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
 
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventspoll/poll"
 	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/pkg/errors"
 )
 
-func loadMongoConfig() (*poll.MongoConfig, error) {
-	hosts := *commonutil.ParseHosts(
-		os.Getenv("MONGO_HOSTS"),
-	)
+// mongoURIOptions returns the connection-string options for the configuration.
+func mongoURIOptions(m *config.Mongo) string {
+	opts := url.Values{}
+	if m.TLS.Enabled {
+		opts.Set("ssl", "true")
+	}
+	if m.TLS.CAFile != "" {
+		opts.Set("sslCertificateAuthorityFile", m.TLS.CAFile)
+	}
+	if m.TLS.CertKeyFile != "" {
+		opts.Set("sslClientCertificateKeyFile", m.TLS.CertKeyFile)
+	}
+	if m.TLS.AllowInvalid {
+		opts.Set("sslInsecure", "true")
+	}
+	if m.AuthSource != "" {
+		opts.Set("authSource", m.AuthSource)
+	}
+	if m.ReplicaSet != "" {
+		opts.Set("replicaSet", m.ReplicaSet)
+	}
+	if m.ReadPreference != "" {
+		opts.Set("readPreference", m.ReadPreference)
+	}
+	if m.WriteConcern != "" {
+		opts.Set("w", m.WriteConcern)
+	}
+	return opts.Encode()
+}
 
-	database := os.Getenv("MONGO_DATABASE")
-	aggCollection := os.Getenv("MONGO_AGG_COLLECTION")
-	metaCollection := os.Getenv("MONGO_META_COLLECTION")
+// mongoClientHosts returns the hosts for the MongoClient. The MongoClient builds
+// the connection-string by joining the hosts, so the connection-string options
+// are appended to the last host.
+func mongoClientHosts(m *config.Mongo) []string {
+	hosts := append([]string{}, m.Hosts...)
+	opts := mongoURIOptions(m)
+	if opts != "" {
+		last := len(hosts) - 1
+		hosts[last] = fmt.Sprintf("%s/?%s", hosts[last], opts)
+	}
+	return hosts
+}
 
-	username := os.Getenv("MONGO_USERNAME")
-	password := os.Getenv("MONGO_PASSWORD")
-	connTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
-	connTimeout, err := strconv.Atoi(connTimeoutStr)
+func loadMongoConfig(m *config.Mongo) (*poll.MongoConfig, error) {
+	err := m.Validate()
 	if err != nil {
-		err = errors.Wrap(err, "Error converting MONGO_CONNECTION_TIMEOUT_MS to integer")
-		log.Println(err)
-		log.Println("A defalt value of 3000 will be used for MONGO_CONNECTION_TIMEOUT_MS")
-		connTimeout = 3000
+		err = errors.Wrap(err, "Invalid MongoDB configuration")
+		return nil, err
 	}
 
 	mongoConfig := mongo.ClientConfig{
-		Hosts:               hosts,
-		Username:            username,
-		Password:            password,
-		TimeoutMilliseconds: uint32(connTimeout),
+		Hosts:               mongoClientHosts(m),
+		Username:            m.Username,
+		Password:            m.Password,
+		TimeoutMilliseconds: m.ConnectionTimeoutMS,
 	}
 
 	// MongoDB Client
 	client, err := mongo.NewClient(mongoConfig)
 	if err != nil {
 		err = errors.Wrap(err, "Error creating MongoClient")
-		log.Fatalln(err)
+		return nil, err
 	}
 
-	resTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
-	resTimeout, err := strconv.Atoi(resTimeoutStr)
-	if err != nil {
-		err = errors.Wrap(err, "Error converting MONGO_RESOURCE_TIMEOUT_MS to integer")
-		log.Println(err)
-		log.Println("A defalt value of 5000 will be used for MONGO_RESOURCE_TIMEOUT_MS")
-		connTimeout = 5000
-	}
 	conn := &mongo.ConnectionConfig{
 		Client:  client,
-		Timeout: uint32(resTimeout),
+		Timeout: m.ResourceTimeoutMS,
 	}
 
-	aggMongoCollection, err := createMongoCollection(conn, database, aggCollection)
+	aggMongoCollection, err := createMongoCollection(
+		conn, m.Database, m.AggCollection, m.SchemaValidation,
+	)
 	if err != nil {
 		err = errors.Wrap(err, "Error creating MongoCollection")
 		return nil, err
@@ -70,39 +97,211 @@ func loadMongoConfig() (*poll.MongoConfig, error) {
 		AggregateID:        flashsale.AggregateID,
 		AggCollection:      aggMongoCollection,
 		Connection:         conn,
-		MetaDatabaseName:   database,
-		MetaCollectionName: metaCollection,
+		MetaDatabaseName:   m.Database,
+		MetaCollectionName: m.MetaCollection,
 	}, nil
 }
 
-func createMongoCollection(
-	conn *mongo.ConnectionConfig, db string, coll string,
-) (*mongo.Collection, error) {
-	// Index Configuration
-	indexConfigs := []mongo.IndexConfig{
-		mongo.IndexConfig{
-			ColumnConfig: []mongo.IndexColumnConfig{
-				mongo.IndexColumnConfig{
-					Name: "flashSaleID",
-				},
+// aggIndexConfigs are the indexes for the aggregate-collection, based on the
+// fields FlashSales are queried by. Indexes are identified by their names,
+// so changing an index requires renaming it.
+var aggIndexConfigs = []mongo.IndexConfig{
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "flashSaleID",
 			},
-			IsUnique: true,
-			Name:     "flashSaleID_index",
 		},
-	}
+		IsUnique: true,
+		Name:     "flashSaleID_index",
+	},
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "items.itemID",
+			},
+		},
+		Name: "items_itemID_index",
+	},
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "items.sku",
+			},
+		},
+		Name: "items_sku_index",
+	},
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "items.lot",
+			},
+		},
+		Name: "items_lot_index",
+	},
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "timestamp",
+			},
+		},
+		Name: "timestamp_index",
+	},
+	mongo.IndexConfig{
+		ColumnConfig: []mongo.IndexColumnConfig{
+			mongo.IndexColumnConfig{
+				Name: "startTime",
+			},
+			mongo.IndexColumnConfig{
+				Name: "endTime",
+			},
+		},
+		Name: "startTime_endTime_index",
+	},
+}
 
-	// Create New Collection
+func createMongoCollection(
+	conn *mongo.ConnectionConfig, db string, coll string, schemaValidation string,
+) (*mongo.Collection, error) {
+	// Create New Collection, this also creates the missing indexes
 	c := &mongo.Collection{
 		Connection:   conn,
 		Database:     db,
 		Name:         coll,
 		SchemaStruct: &flashsale.FlashSale{},
-		Indexes:      indexConfigs,
+		Indexes:      aggIndexConfigs,
 	}
 	collection, err := mongo.EnsureCollection(c)
 	if err != nil {
 		err = errors.Wrap(err, "Error creating MongoCollection")
 		return nil, err
 	}
+
+	err = applySchemaValidator(conn, db, coll, schemaValidation)
+	if err != nil {
+		err = errors.Wrap(err, "Error applying schema-validator")
+		return nil, err
+	}
+
+	unexpected, err := unexpectedIndexes(conn, db, coll, aggIndexConfigs)
+	if err != nil {
+		// Not fatal, since the collection and its indexes are ready
+		err = errors.Wrap(err, "Error checking indexes")
+		log.Println(err)
+	}
+	for _, name := range unexpected {
+		log.Printf(
+			"Index %s on %s.%s is not in the index-configuration, "+
+				"it will not be dropped, consider removing it manually",
+			name, db, coll,
+		)
+	}
 	return collection, nil
 }
+
+// unexpectedIndexes returns the names of the indexes on the collection which
+// are not in the index-configurations. The default "_id_" index is expected.
+func unexpectedIndexes(
+	conn *mongo.ConnectionConfig,
+	db string,
+	coll string,
+	indexConfigs []mongo.IndexConfig,
+) ([]string, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(conn.Timeout)*time.Millisecond,
+	)
+	defer cancel()
+
+	cursor, err := conn.Client.Database(db).Collection(coll).Indexes().List(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Error listing indexes")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	names := make([]string, 0)
+	for cursor.Next(ctx) {
+		index := &struct {
+			Name string `bson:"name"`
+		}{}
+		err = cursor.Decode(index)
+		if err != nil {
+			err = errors.Wrap(err, "Error decoding index")
+			return nil, err
+		}
+		names = append(names, index.Name)
+	}
+	err = cursor.Err()
+	if err != nil {
+		err = errors.Wrap(err, "Error iterating indexes")
+		return nil, err
+	}
+	return unconfiguredIndexes(names, indexConfigs), nil
+}
+
+// unconfiguredIndexes returns the index-names which are not in the
+// index-configurations, other than the default "_id_" index.
+func unconfiguredIndexes(names []string, indexConfigs []mongo.IndexConfig) []string {
+	isExpected := map[string]bool{
+		"_id_": true,
+	}
+	for _, ic := range indexConfigs {
+		isExpected[ic.Name] = true
+	}
+
+	unexpected := make([]string, 0)
+	for _, name := range names {
+		if !isExpected[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	return unexpected
+}
+
+// applySchemaValidator sets the FlashSale $jsonSchema as validator on collection.
+// In "strict" mode (default), invalid inserts and updates are rejected. In "warn"
+// mode, those are only logged by MongoDB. The validator is removed in "off" mode.
+func applySchemaValidator(
+	conn *mongo.ConnectionConfig,
+	db string,
+	coll string,
+	mode string,
+) error {
+	validator := map[string]interface{}{
+		"$jsonSchema": flashsale.JSONSchema(),
+	}
+	validationAction := "error"
+	validationLevel := "strict"
+
+	switch mode {
+	case "", "strict":
+	case "warn":
+		validationAction = "warn"
+	case "off":
+		validator = map[string]interface{}{}
+		validationLevel = "off"
+	default:
+		return errors.Errorf("invalid MONGO_SCHEMA_VALIDATION: %s", mode)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(conn.Timeout)*time.Millisecond,
+	)
+	defer cancel()
+
+	// Command-document needs ordered keys, hence not using a map
+	cmd := bson.NewDocument(
+		bson.EC.String("collMod", coll),
+		bson.EC.Interface("validator", validator),
+		bson.EC.String("validationLevel", validationLevel),
+		bson.EC.String("validationAction", validationAction),
+	)
+	_, err := conn.Client.Database(db).RunCommand(ctx, cmd)
+	if err != nil {
+		err = errors.Wrap(err, "Error running collMod")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MongoConfig", func() {
+	Describe("aggIndexConfigs", func() {
+		It("should have uniquely named indexes with columns", func() {
+			names := map[string]bool{}
+			for _, ic := range aggIndexConfigs {
+				Expect(ic.Name).ToNot(BeEmpty())
+				Expect(names).ToNot(HaveKey(ic.Name))
+				Expect(ic.ColumnConfig).ToNot(BeEmpty())
+				names[ic.Name] = true
+			}
+		})
+
+		It("should have a unique index on flashSaleID", func() {
+			var flashSaleIndex *mongo.IndexConfig
+			for i, ic := range aggIndexConfigs {
+				if len(ic.ColumnConfig) == 1 && ic.ColumnConfig[0].Name == "flashSaleID" {
+					flashSaleIndex = &aggIndexConfigs[i]
+				}
+			}
+			Expect(flashSaleIndex).ToNot(BeNil())
+			Expect(flashSaleIndex.IsUnique).To(BeTrue())
+		})
+	})
+
+	Describe("unconfiguredIndexes", func() {
+		It("should return the indexes not in index-configurations", func() {
+			names := []string{
+				"_id_",
+				"flashSaleID_index",
+				"items_sku_index",
+				"region_index",
+				"items_upc_index",
+			}
+			Expect(unconfiguredIndexes(names, aggIndexConfigs)).To(Equal([]string{
+				"region_index",
+				"items_upc_index",
+			}))
+		})
+
+		It("should only expect the default index without index-configurations", func() {
+			names := []string{"_id_", "flashSaleID_index"}
+			Expect(unconfiguredIndexes(names, nil)).To(Equal([]string{
+				"flashSaleID_index",
+			}))
+		})
+
+		It("should return empty slice when all indexes are configured", func() {
+			names := []string{"_id_"}
+			for _, ic := range aggIndexConfigs {
+				names = append(names, ic.Name)
+			}
+			Expect(unconfiguredIndexes(names, aggIndexConfigs)).To(BeEmpty())
+		})
+	})
+})
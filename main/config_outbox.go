@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// loadOutboxRelay creates the OutboxRelay for the aggregate-collection.
+// A sync-producer is used, so each message is acknowledged before the
+// OutboxEntry is marked as sent.
+func loadOutboxRelay(
+	cfg *config.Config,
+	aggCollection *mongo.Collection,
+) (*flashsale.OutboxRelay, sarama.SyncProducer, error) {
+	saramaConfig, err := cfg.Kafka.SaramaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating outbox-producer config")
+		return nil, nil, err
+	}
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Return.Errors = true
+	syncProducer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, saramaConfig)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating outbox-producer")
+		return nil, nil, err
+	}
+
+	relay, err := flashsale.NewOutboxRelay(cfg, aggCollection, syncProducer)
+	if err != nil {
+		syncProducer.Close()
+		err = errors.Wrap(err, "Error creating OutboxRelay")
+		return nil, nil, err
+	}
+	return relay, syncProducer, nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"time"
+
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
+	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
+)
+
+func loadRecurrenceConfig(cfg *config.Config) *flashsale.RecurrenceConfig {
+	return &flashsale.RecurrenceConfig{
+		Interval:      time.Duration(cfg.Recurrence.IntervalSec) * time.Second,
+		LookaheadDays: cfg.Recurrence.LookaheadDays,
+	}
+}
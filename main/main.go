@@ -7,63 +7,45 @@ import (
 	"github.com/TerrexTech/go-agg-framer/framer"
 	"github.com/TerrexTech/go-kafkautils/kafka"
 
+	"github.com/TerrexTech/agg-flashsale-cmd/config"
 	"github.com/TerrexTech/agg-flashsale-cmd/flashsale"
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventspoll/poll"
-	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 )
 
-func validateEnv() error {
-	missingVar, err := commonutil.ValidateEnv(
-		"KAFKA_BROKERS",
-
-		"KAFKA_CONSUMER_EVENT_GROUP",
-		"KAFKA_CONSUMER_EVENT_QUERY_GROUP",
-
-		"KAFKA_CONSUMER_EVENT_TOPIC",
-		"KAFKA_CONSUMER_EVENT_QUERY_TOPIC",
-		"KAFKA_PRODUCER_EVENT_TOPIC",
-		"KAFKA_PRODUCER_EVENT_QUERY_TOPIC",
-		"KAFKA_PRODUCER_RESPONSE_TOPIC",
-
-		"MONGO_HOSTS",
-		"MONGO_DATABASE",
-		"MONGO_AGG_COLLECTION",
-		"MONGO_META_COLLECTION",
-
-		"MONGO_CONNECTION_TIMEOUT_MS",
-		"MONGO_RESOURCE_TIMEOUT_MS",
-	)
-
+func main() {
+	log.Println("Reading configuration")
+	cfg, err := config.Load("./.env")
 	if err != nil {
-		err = errors.Wrapf(err, "Env-var %s is required for testing, but is not set", missingVar)
-		return err
+		err = errors.Wrap(err, "Error loading configuration")
+		log.Fatalln(err)
 	}
-	return nil
-}
 
-func main() {
-	log.Println("Reading environment file")
-	err := godotenv.Load("./.env")
-	if err != nil {
-		err = errors.Wrap(err,
-			".env file not found, env-vars will be read as set in environment",
-		)
-		log.Println(err)
+	if len(os.Args) > 1 {
+		err = runCommand(cfg, os.Args[1], os.Args[2:])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
 	}
 
-	err = validateEnv()
+	err = cfg.Validate()
 	if err != nil {
 		log.Fatalln(err)
 	}
+	log.Printf("Effective configuration:\n%s", cfg)
 
-	kc, err := loadKafkaConfig()
+	if cfg.Metrics.Addr != "" {
+		log.Printf("Serving metrics on %s/debug/vars", cfg.Metrics.Addr)
+		go serveMetrics(cfg.Metrics.Addr)
+	}
+
+	kc, err := loadKafkaConfig(&cfg.Kafka)
 	if err != nil {
 		err = errors.Wrap(err, "Error in KafkaConfig")
 		log.Fatalln(err)
 	}
-	mc, err := loadMongoConfig()
+	mc, err := loadMongoConfig(&cfg.Mongo)
 	if err != nil {
 		err = errors.Wrap(err, "Error in MongoConfig")
 		log.Fatalln(err)
@@ -73,6 +55,7 @@ func main() {
 			EnableInsert: true,
 			EnableUpdate: true,
 			EnableDelete: true,
+			EnableQuery:  true,
 		},
 		KafkaConfig: *kc,
 		MongoConfig: *mc,
@@ -84,14 +67,80 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	framerSaramaConfig, err := cfg.Kafka.SaramaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error in KafkaConfig")
+		log.Fatalln(err)
+	}
 	prodConfig := &kafka.ProducerConfig{
-		KafkaBrokers: *commonutil.ParseHosts(os.Getenv("KAFKA_BROKERS")),
+		KafkaBrokers: cfg.Kafka.Brokers,
+		SaramaConfig: framerSaramaConfig,
 	}
 	topicConfig := &framer.TopicConfig{
-		DocumentTopic: os.Getenv("KAFKA_PRODUCER_RESPONSE_TOPIC"),
+		DocumentTopic: cfg.Kafka.ProducerResponseTopic,
 	}
 	frm, err := framer.New(eventPoll.Context(), prodConfig, topicConfig)
 
+	execContext := flashsale.NewExecContext(cfg)
+	execContext.Use(
+		flashsale.Recover(),
+		flashsale.Logging(),
+		flashsale.Timing(),
+		flashsale.Dedup(cfg.Handlers.DedupSize),
+	)
+	if cfg.Authz.Enabled {
+		resolver, err := flashsale.NewStaticRoleResolver(cfg.Authz.RolesFile)
+		if err != nil {
+			err = errors.Wrap(err, "Error in Authz")
+			log.Fatalln(err)
+		}
+		execContext.Use(flashsale.Authorize(resolver, flashsale.DefaultPolicy()))
+	}
+	for _, key := range execContext.Registry().List() {
+		log.Printf(
+			"Registered handler for EventAction: %s, ServiceAction: %s",
+			key.EventAction, key.ServiceAction,
+		)
+	}
+
+	relay, outboxProducer, err := loadOutboxRelay(cfg, mc.AggCollection)
+	if err != nil {
+		err = errors.Wrap(err, "Error in OutboxRelay")
+		log.Fatalln(err)
+	}
+	defer outboxProducer.Close()
+	log.Println("Starting OutboxRelay")
+	go relay.Run(eventPoll.Context())
+
+	if cfg.Expiry.Enabled {
+		ec, err := loadExpiryConfig(cfg, mc.Connection, mc.MetaDatabaseName)
+		if err != nil {
+			err = errors.Wrap(err, "Error in ExpiryConfig")
+			log.Fatalln(err)
+		}
+		scheduler, err := flashsale.NewExpiryScheduler(
+			execContext, mc.AggCollection, ec,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error creating ExpiryScheduler")
+			log.Fatalln(err)
+		}
+		log.Println("Starting ExpiryScheduler")
+		go scheduler.Run(eventPoll.Context(), frm.Document)
+	}
+
+	if cfg.Recurrence.Enabled {
+		scheduler, err := flashsale.NewRecurrenceScheduler(
+			execContext, mc.AggCollection, loadRecurrenceConfig(cfg),
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error creating RecurrenceScheduler")
+			log.Fatalln(err)
+		}
+		log.Println("Starting RecurrenceScheduler")
+		go scheduler.Run(eventPoll.Context())
+	}
+
 	for {
 		select {
 		case err := <-eventPoll.Wait():
@@ -109,7 +158,7 @@ func main() {
 					log.Println(err)
 					return
 				}
-				kafkaResp := flashsale.Delete(mc.AggCollection, &eventResp.Event)
+				kafkaResp := execContext.Handle(mc.AggCollection, &eventResp.Event)
 				if kafkaResp != nil {
 					frm.Document <- kafkaResp
 				}
@@ -126,7 +175,7 @@ func main() {
 					log.Println(err)
 					return
 				}
-				kafkaResp := flashsale.Insert(mc.AggCollection, &eventResp.Event)
+				kafkaResp := execContext.Handle(mc.AggCollection, &eventResp.Event)
 				if kafkaResp != nil {
 					frm.Document <- kafkaResp
 				}
@@ -143,7 +192,24 @@ func main() {
 					log.Println(err)
 					return
 				}
-				kafkaResp := flashsale.Update(mc.AggCollection, &eventResp.Event)
+				kafkaResp := execContext.Handle(mc.AggCollection, &eventResp.Event)
+				if kafkaResp != nil {
+					frm.Document <- kafkaResp
+				}
+			}(eventResp)
+
+		case eventResp := <-eventPoll.Query():
+			go func(eventResp *poll.EventResponse) {
+				if eventResp == nil {
+					return
+				}
+				err := eventResp.Error
+				if err != nil {
+					err = errors.Wrap(err, "Error in Query-EventResponse")
+					log.Println(err)
+					return
+				}
+				kafkaResp := execContext.Handle(mc.AggCollection, &eventResp.Event)
 				if kafkaResp != nil {
 					frm.Document <- kafkaResp
 				}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	// Registers the "/debug/vars" handler for expvar-metrics
+	_ "expvar"
+	"log"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// serveMetrics serves the expvar-metrics, such as the count of panics
+// recovered from Event-handlers, on the address.
+func serveMetrics(addr string) {
+	err := http.ListenAndServe(addr, nil)
+	if err != nil {
+		err = errors.Wrap(err, "Error serving metrics")
+		log.Println(err)
+	}
+}